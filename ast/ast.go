@@ -3,6 +3,9 @@ package ast
 import (
 	"bytes"
 	"fmt"
+	"math/big"
+	"regexp"
+	"strings"
 
 	"github.com/shozawa/monkey/token"
 )
@@ -66,7 +69,10 @@ func (r *ReturnStatement) TokenLiteral() string {
 	return r.Token.Literal
 }
 func (r *ReturnStatement) String() string {
-	return "TODO"
+	if r.ReturnValue == nil {
+		return "return;"
+	}
+	return fmt.Sprintf("return %s;", r.ReturnValue.String())
 }
 
 type ExpressionStatement struct {
@@ -107,6 +113,20 @@ func (i *IntegerLiteral) String() string {
 	return i.TokenLiteral()
 }
 
+// BigIntegerLiteral is an integer literal too large for an int64.
+type BigIntegerLiteral struct {
+	Token token.Token
+	Value *big.Int
+}
+
+func (b *BigIntegerLiteral) expressionNode() {}
+func (b *BigIntegerLiteral) TokenLiteral() string {
+	return b.Token.Literal
+}
+func (b *BigIntegerLiteral) String() string {
+	return b.TokenLiteral()
+}
+
 type BoolLiteral struct {
 	Token token.Token
 	Value string
@@ -127,7 +147,64 @@ type StringLiteral struct {
 
 func (s *StringLiteral) expressionNode()      {}
 func (s *StringLiteral) TokenLiteral() string { return s.Token.Literal }
-func (s *StringLiteral) String() string       { return s.TokenLiteral() }
+func (s *StringLiteral) String() string       { return Quote(s.Value) }
+
+// RegexLiteral is a /pattern/ literal. The parser compiles the pattern
+// so that invalid ones are reported with the other syntax errors.
+type RegexLiteral struct {
+	Token  token.Token
+	Value  string
+	Regexp *regexp.Regexp
+}
+
+func (r *RegexLiteral) expressionNode()      {}
+func (r *RegexLiteral) TokenLiteral() string { return r.Token.Literal }
+func (r *RegexLiteral) String() string       { return QuoteRegex(r.Value) }
+
+// QuoteRegex returns pattern as a regex literal, escaping the slashes
+// that would end it.
+func QuoteRegex(pattern string) string {
+	var out strings.Builder
+	out.WriteByte('/')
+	for i := 0; i < len(pattern); i++ {
+		switch ch := pattern[i]; {
+		case ch == '\\' && i+1 < len(pattern):
+			out.WriteByte(ch)
+			i++
+			out.WriteByte(pattern[i])
+		case ch == '/':
+			out.WriteString("\\/")
+		default:
+			out.WriteByte(ch)
+		}
+	}
+	out.WriteByte('/')
+	return out.String()
+}
+
+// Quote returns s as a string literal, escaping the characters the
+// lexer decodes.
+func Quote(s string) string {
+	var out strings.Builder
+	out.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		switch ch := s[i]; ch {
+		case '"', '\\':
+			out.WriteByte('\\')
+			out.WriteByte(ch)
+		case '\n':
+			out.WriteString("\\n")
+		case '\t':
+			out.WriteString("\\t")
+		case '\r':
+			out.WriteString("\\r")
+		default:
+			out.WriteByte(ch)
+		}
+	}
+	out.WriteByte('"')
+	return out.String()
+}
 
 type PrefixExpression struct {
 	Token    token.Token
@@ -161,6 +238,7 @@ func (i *Infix) String() string {
 type BlockStatement struct {
 	Token      token.Token
 	Statements []Statement
+	Rbrace     token.Token
 }
 
 func (b *BlockStatement) statementNode() {}
@@ -168,7 +246,14 @@ func (b *BlockStatement) TokenLiteral() string {
 	return b.Token.Literal
 }
 func (b *BlockStatement) String() string {
-	return "TODO"
+	var out bytes.Buffer
+	out.WriteString("{ ")
+	for _, stmt := range b.Statements {
+		out.WriteString(stmt.String())
+		out.WriteString(" ")
+	}
+	out.WriteString("}")
+	return out.String()
 }
 
 type IfExpression struct {
@@ -183,7 +268,11 @@ func (i *IfExpression) TokenLiteral() string {
 	return i.Token.Literal
 }
 func (b *IfExpression) String() string {
-	return "TODO"
+	s := fmt.Sprintf("if (%s) %s", b.Condition.String(), b.Consequence.String())
+	if b.Alternative != nil {
+		s += " else " + b.Alternative.String()
+	}
+	return s
 }
 
 type FunctionLiteral struct {
@@ -197,13 +286,18 @@ func (f *FunctionLiteral) TokenLiteral() string {
 	return f.Token.Literal
 }
 func (f *FunctionLiteral) String() string {
-	return "TODO"
+	params := make([]string, len(f.Parameters))
+	for i, p := range f.Parameters {
+		params[i] = p.String()
+	}
+	return fmt.Sprintf("fn(%s) %s", strings.Join(params, ", "), f.Body.String())
 }
 
 type CallExpression struct {
 	Token     token.Token
 	Function  Expression
 	Arguments []Expression
+	Rparen    token.Token
 }
 
 func (c *CallExpression) expressionNode() {}
@@ -211,5 +305,131 @@ func (c *CallExpression) TokenLiteral() string {
 	return c.Token.Literal
 }
 func (c *CallExpression) String() string {
-	return "TODO"
+	args := make([]string, len(c.Arguments))
+	for i, a := range c.Arguments {
+		args[i] = a.String()
+	}
+	return fmt.Sprintf("%s(%s)", c.Function.String(), strings.Join(args, ", "))
+}
+
+type MemberExpression struct {
+	Token    token.Token
+	Object   Expression
+	Property *Identifier
+}
+
+func (m *MemberExpression) expressionNode() {}
+func (m *MemberExpression) TokenLiteral() string {
+	return m.Token.Literal
+}
+func (m *MemberExpression) String() string {
+	return fmt.Sprintf("%s.%s", m.Object.String(), m.Property.String())
+}
+
+type ArrayLiteral struct {
+	Token    token.Token
+	Elements []Expression
+	Rbracket token.Token
+}
+
+func (a *ArrayLiteral) expressionNode() {}
+func (a *ArrayLiteral) TokenLiteral() string {
+	return a.Token.Literal
+}
+func (a *ArrayLiteral) String() string {
+	elements := make([]string, len(a.Elements))
+	for i, e := range a.Elements {
+		elements[i] = e.String()
+	}
+	return fmt.Sprintf("[%s]", strings.Join(elements, ", "))
+}
+
+type IndexExpression struct {
+	Token token.Token
+	Left  Expression
+	Index Expression
+}
+
+func (i *IndexExpression) expressionNode() {}
+func (i *IndexExpression) TokenLiteral() string {
+	return i.Token.Literal
+}
+func (i *IndexExpression) String() string {
+	return fmt.Sprintf("(%s[%s])", i.Left.String(), i.Index.String())
+}
+
+type HashLiteral struct {
+	Token  token.Token
+	Pairs  []HashPair
+	Rbrace token.Token
+}
+
+// HashPair is a key and its value in a hash literal, kept in source
+// order.
+type HashPair struct {
+	Key   Expression
+	Value Expression
+}
+
+func (h *HashLiteral) expressionNode() {}
+func (h *HashLiteral) TokenLiteral() string {
+	return h.Token.Literal
+}
+func (h *HashLiteral) String() string {
+	pairs := make([]string, len(h.Pairs))
+	for i, pair := range h.Pairs {
+		pairs[i] = pair.Key.String() + ": " + pair.Value.String()
+	}
+	return fmt.Sprintf("{%s}", strings.Join(pairs, ", "))
+}
+
+// Pos returns the position of the first token of node.
+func Pos(node Node) token.Position {
+	switch node := node.(type) {
+	case *Program:
+		if len(node.Statements) > 0 {
+			return Pos(node.Statements[0])
+		}
+	case *LetStatement:
+		return node.Token.Position()
+	case *ReturnStatement:
+		return node.Token.Position()
+	case *ExpressionStatement:
+		if node.Expression != nil {
+			return Pos(node.Expression)
+		}
+	case *BlockStatement:
+		return node.Token.Position()
+	case *Identifier:
+		return node.Token.Position()
+	case *IntegerLiteral:
+		return node.Token.Position()
+	case *BigIntegerLiteral:
+		return node.Token.Position()
+	case *BoolLiteral:
+		return node.Token.Position()
+	case *StringLiteral:
+		return node.Token.Position()
+	case *RegexLiteral:
+		return node.Token.Position()
+	case *PrefixExpression:
+		return node.Token.Position()
+	case *Infix:
+		return Pos(node.Left)
+	case *IfExpression:
+		return node.Token.Position()
+	case *FunctionLiteral:
+		return node.Token.Position()
+	case *CallExpression:
+		return Pos(node.Function)
+	case *MemberExpression:
+		return Pos(node.Object)
+	case *ArrayLiteral:
+		return node.Token.Position()
+	case *HashLiteral:
+		return node.Token.Position()
+	case *IndexExpression:
+		return Pos(node.Left)
+	}
+	return token.Position{}
 }
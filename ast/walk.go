@@ -0,0 +1,70 @@
+package ast
+
+// Inspect walks the tree rooted at node in source order, calling f for
+// each node. When f returns false the node's children are skipped.
+func Inspect(node Node, f func(Node) bool) {
+	if node == nil || !f(node) {
+		return
+	}
+	switch node := node.(type) {
+	case *Program:
+		for _, stmt := range node.Statements {
+			Inspect(stmt, f)
+		}
+	case *LetStatement:
+		Inspect(node.Name, f)
+		inspectExpression(node.Value, f)
+	case *ReturnStatement:
+		inspectExpression(node.ReturnValue, f)
+	case *ExpressionStatement:
+		inspectExpression(node.Expression, f)
+	case *BlockStatement:
+		for _, stmt := range node.Statements {
+			Inspect(stmt, f)
+		}
+	case *PrefixExpression:
+		inspectExpression(node.Right, f)
+	case *Infix:
+		inspectExpression(node.Left, f)
+		inspectExpression(node.Right, f)
+	case *IfExpression:
+		inspectExpression(node.Condition, f)
+		Inspect(node.Consequence, f)
+		if node.Alternative != nil {
+			Inspect(node.Alternative, f)
+		}
+	case *FunctionLiteral:
+		for _, param := range node.Parameters {
+			Inspect(param, f)
+		}
+		Inspect(node.Body, f)
+	case *CallExpression:
+		inspectExpression(node.Function, f)
+		for _, arg := range node.Arguments {
+			inspectExpression(arg, f)
+		}
+	case *MemberExpression:
+		inspectExpression(node.Object, f)
+		Inspect(node.Property, f)
+	case *ArrayLiteral:
+		for _, e := range node.Elements {
+			inspectExpression(e, f)
+		}
+	case *IndexExpression:
+		inspectExpression(node.Left, f)
+		inspectExpression(node.Index, f)
+	case *HashLiteral:
+		for _, pair := range node.Pairs {
+			inspectExpression(pair.Key, f)
+			inspectExpression(pair.Value, f)
+		}
+	}
+}
+
+// inspectExpression skips absent expressions, which would otherwise be
+// non-nil Nodes wrapping nil.
+func inspectExpression(exp Expression, f func(Node) bool) {
+	if exp != nil {
+		Inspect(exp, f)
+	}
+}
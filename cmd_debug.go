@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/shozawa/monkey/debugger"
+	"github.com/shozawa/monkey/evaluator"
+	"github.com/shozawa/monkey/lexer"
+	"github.com/shozawa/monkey/object"
+	"github.com/shozawa/monkey/parser"
+	"github.com/shozawa/monkey/prelude"
+)
+
+// runDebug implements "monkey debug [-no-prelude] file [arg ...]" and
+// "monkey debug [-no-prelude] -dap". The first debugs file with args
+// from the terminal, stopping before its first statement; the program
+// shares standard input with the debugger's prompt. The second serves
+// the Debug Adapter Protocol on standard input and output.
+func runDebug(args []string) int {
+	flags := flag.NewFlagSet("debug", flag.ContinueOnError)
+	dap := flags.Bool("dap", false, "speak the Debug Adapter Protocol over stdio")
+	noPrelude := flags.Bool("no-prelude", false, noPreludeUsage)
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: monkey debug [-no-prelude] file [arg ...] | monkey debug [-no-prelude] -dap")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *dap {
+		server := debugger.NewDAP(os.Stdin, os.Stdout)
+		server.NoPrelude = *noPrelude
+		if err := server.Serve(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		return 0
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return 2
+	}
+
+	path := flags.Arg(0)
+	src, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	p := parser.New(lexer.New(string(src)))
+	program := p.Parse()
+	if errs := p.Errors(); len(errs) > 0 {
+		for _, msg := range errs {
+			fmt.Fprintf(os.Stderr, "%s:%s\n", path, msg)
+		}
+		return 1
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	in := bufio.NewReader(os.Stdin)
+	terminal := debugger.NewTerminal(in, os.Stdout, string(src))
+	rt := &object.Runtime{
+		Capabilities: object.CAP_ALL,
+		Stdin:        in,
+		Stdout:       os.Stdout,
+		Stderr:       os.Stderr,
+		Tracer:       terminal.Debugger(),
+	}
+	if !*noPrelude {
+		rt.Prelude = prelude.Env()
+	}
+	env := object.NewEnvWithRuntime(rt)
+	env.SetFile(&object.File{Path: abs})
+	env.Set(evaluator.ARGS, evaluator.NewArgs(flags.Args()[1:]))
+	if result, ok := terminal.Debugger().Run(&program, env, true).(*object.Error); ok {
+		fmt.Fprintln(os.Stderr, result.Inspect())
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/shozawa/monkey/diff"
+	"github.com/shozawa/monkey/format"
+)
+
+const MONKEY_EXT = ".monkey"
+
+// runFmt implements "monkey fmt [-l] [-d] [path ...]". Files are
+// rewritten in place; directories are searched for .monkey files. With
+// no paths it formats standard input to standard output.
+func runFmt(args []string) int {
+	flags := flag.NewFlagSet("fmt", flag.ContinueOnError)
+	list := flags.Bool("l", false, "list files whose formatting differs, don't rewrite them")
+	showDiff := flags.Bool("d", false, "print diffs instead of rewriting files")
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: monkey fmt [-l] [-d] [path ...]")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	if flags.NArg() == 0 {
+		src, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+		formatted, err := format.Source(src)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "<stdin>:%s\n", err)
+			return 2
+		}
+		os.Stdout.Write(formatted)
+		return 0
+	}
+
+	status := 0
+	for _, path := range flags.Args() {
+		files, err := monkeyFiles(path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			status = 2
+			continue
+		}
+		for _, file := range files {
+			if err := fmtFile(file, *list, *showDiff); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				status = 2
+			}
+		}
+	}
+	return status
+}
+
+func fmtFile(path string, list, showDiff bool) error {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	formatted, err := format.Source(src)
+	if err != nil {
+		return fmt.Errorf("%s:%s", path, err)
+	}
+	if bytes.Equal(src, formatted) {
+		return nil
+	}
+	if list {
+		fmt.Println(path)
+	}
+	if showDiff {
+		fmt.Print(diff.Unified(path+".orig", path, string(src), string(formatted)))
+	}
+	if list || showDiff {
+		return nil
+	}
+	return os.WriteFile(path, formatted, 0644)
+}
+
+// monkeyFiles returns path itself, or the .monkey files below it when
+// it is a directory.
+func monkeyFiles(path string) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+	var files []string
+	err = filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && filepath.Ext(p) == MONKEY_EXT {
+			files = append(files, p)
+		}
+		return nil
+	})
+	return files, err
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/shozawa/monkey/lint"
+)
+
+// runLint implements "monkey lint [-disable rule,...] [path ...]". It
+// exits with 1 when issues are found and 2 when files can't be checked.
+// With no paths it checks standard input.
+func runLint(args []string) int {
+	flags := flag.NewFlagSet("lint", flag.ContinueOnError)
+	disable := flags.String("disable", "", "comma-separated rules not to report: "+strings.Join(lint.Rules, ", "))
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: monkey lint [-disable rule,...] [path ...]")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	config := &lint.Config{Disabled: make(map[string]bool)}
+	for _, rule := range strings.Split(*disable, ",") {
+		if rule != "" {
+			config.Disabled[rule] = true
+		}
+	}
+
+	if flags.NArg() == 0 {
+		src, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+		return lintSource(config, "<stdin>", src)
+	}
+
+	status := 0
+	for _, path := range flags.Args() {
+		files, err := monkeyFiles(path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			status = 2
+			continue
+		}
+		for _, file := range files {
+			src, err := os.ReadFile(file)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				status = 2
+				continue
+			}
+			if s := lintSource(config, file, src); s > status {
+				status = s
+			}
+		}
+	}
+	return status
+}
+
+func lintSource(config *lint.Config, name string, src []byte) int {
+	issues, err := config.Source(src)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s:%s\n", name, err)
+		return 2
+	}
+	for _, issue := range issues {
+		fmt.Printf("%s:%s\n", name, issue)
+	}
+	if len(issues) > 0 {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,22 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/shozawa/monkey/lsp"
+)
+
+// runLsp implements "monkey lsp", serving the Language Server Protocol
+// over standard input and output.
+func runLsp(args []string) int {
+	if len(args) > 0 {
+		fmt.Fprintln(os.Stderr, "usage: monkey lsp")
+		return 2
+	}
+	if err := lsp.NewServer(os.Stdin, os.Stdout).Run(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/shozawa/monkey/interpreter"
+	"github.com/shozawa/monkey/profile"
+	"github.com/shozawa/monkey/repl"
+)
+
+// noPreludeUsage is the help of the -no-prelude flag, which every
+// command running programs takes.
+const noPreludeUsage = "leave out the prelude functions written in Monkey, such as sum"
+
+// runScript implements "monkey [-profile file] [-no-prelude] [script [arg ...]]",
+// running script with args, or the REPL when there is none.
+func runScript(args []string) int {
+	flags := flag.NewFlagSet("monkey", flag.ContinueOnError)
+	profilePath := flags.String("profile", "", "write a pprof profile of the script to `file`")
+	noPrelude := flags.Bool("no-prelude", false, noPreludeUsage)
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: monkey [-profile file] [-no-prelude] [script [arg ...]]")
+		fmt.Fprintln(os.Stderr, "       monkey fmt|lint|lsp|debug|test ...")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() == 0 {
+		if *profilePath != "" {
+			fmt.Fprintln(os.Stderr, "-profile needs a script")
+			return 2
+		}
+		return (&repl.Config{NoPrelude: *noPrelude}).Start(os.Stdin, os.Stdout, os.Stderr)
+	}
+
+	config := &interpreter.Config{Stdin: os.Stdin, Args: flags.Args()[1:], NoPrelude: *noPrelude}
+	var profiler *profile.Profiler
+	if *profilePath != "" {
+		profiler = profile.New()
+		config.Tracer = profiler.Tracer()
+	}
+	path := flags.Arg(0)
+	status := 0
+	err := config.ExecuteFile(path, os.Stdout, os.Stderr)
+	if exitErr, ok := err.(*interpreter.ExitError); ok {
+		status = exitErr.Code
+	} else if err != nil {
+		fmt.Printf("can't open file: %q\n", path)
+		return 1
+	}
+	if profiler != nil {
+		if err := writeProfile(profiler, *profilePath); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	}
+	return status
+}
+
+func writeProfile(profiler *profile.Profiler, path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := profiler.WritePprof(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/shozawa/monkey/cover"
+	"github.com/shozawa/monkey/tester"
+)
+
+// runTest implements "monkey test [-v] [-cover] [path ...]", running
+// the test functions of the _test.monkey files in each path, the
+// current directory by default. With -cover it reports how much of the
+// code under test ran.
+func runTest(args []string) int {
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	verbose := flags.Bool("v", false, "report passing tests too")
+	coverMode := flags.Bool("cover", false, "report the coverage of the code the tests run")
+	lcovPath := flags.String("coverprofile", "", "write an lcov coverage report to `file`; implies -cover")
+	htmlPath := flags.String("coverhtml", "", "write an HTML coverage report to `file`; implies -cover")
+	noPrelude := flags.Bool("no-prelude", false, noPreludeUsage)
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: monkey test [-v] [-cover] [-coverprofile file] [-coverhtml file] [path ...]")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	paths := flags.Args()
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+	var files []string
+	for _, path := range paths {
+		found, err := testFiles(path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+		files = append(files, found...)
+	}
+	if len(files) == 0 {
+		fmt.Fprintln(os.Stderr, "no test files")
+		return 2
+	}
+
+	runner := &tester.Runner{Out: os.Stdout, Verbose: *verbose, NoPrelude: *noPrelude}
+	var coverage *cover.Coverage
+	if *coverMode || *lcovPath != "" || *htmlPath != "" {
+		coverage = cover.New()
+		runner.Tracer = coverage
+	}
+	status := 0
+	for _, file := range files {
+		if !runner.RunFile(file) {
+			status = 1
+		}
+	}
+	if coverage == nil {
+		return status
+	}
+
+	// Like Go, leave the tests themselves out of the coverage.
+	var covered []*cover.File
+	for _, f := range coverage.Files() {
+		if !strings.HasSuffix(f.Path, tester.TEST_SUFFIX) {
+			covered = append(covered, f)
+		}
+	}
+	for _, f := range covered {
+		fmt.Printf("coverage: %s: %.1f%% of statements, %.1f%% of branches\n", f.Path, f.StatementPercent(), f.BranchPercent())
+	}
+	if *lcovPath != "" {
+		if err := writeReport(*lcovPath, covered, cover.WriteLcov); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+	}
+	if *htmlPath != "" {
+		if err := writeReport(*htmlPath, covered, cover.WriteHTML); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+	}
+	return status
+}
+
+// testFiles returns path if it is a file, or else the test files in the
+// directory tree it names.
+func testFiles(path string) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+	all, err := monkeyFiles(path)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, f := range all {
+		if strings.HasSuffix(f, tester.TEST_SUFFIX) {
+			files = append(files, f)
+		}
+	}
+	return files, nil
+}
+
+func writeReport(path string, files []*cover.File, write func(io.Writer, []*cover.File) error) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := write(f, files); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,207 @@
+package cover
+
+import (
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/shozawa/monkey/ast"
+	"github.com/shozawa/monkey/lexer"
+	"github.com/shozawa/monkey/object"
+	"github.com/shozawa/monkey/parser"
+	"github.com/shozawa/monkey/token"
+)
+
+// Statement is a statement of a file and the number of times it ran.
+type Statement struct {
+	Pos   token.Position
+	Count int64
+}
+
+// Branch is an if expression and the number of times each of its
+// branches ran. An if without else still has an Alternative: doing
+// nothing.
+type Branch struct {
+	Pos         token.Position
+	Consequence int64
+	Alternative int64
+}
+
+// File is the coverage of one source file.
+type File struct {
+	Path       string
+	Source     string
+	Statements []*Statement
+	Branches   []*Branch
+
+	statements map[token.Position]*Statement
+	branches   map[token.Position]*Branch
+}
+
+func newFile(path, source string) *File {
+	f := &File{
+		Path:       path,
+		Source:     source,
+		statements: make(map[token.Position]*Statement),
+		branches:   make(map[token.Position]*Branch),
+	}
+	program := parser.New(lexer.New(source)).Parse()
+	addStatements := func(stmts []ast.Statement) {
+		for _, stmt := range stmts {
+			s := &Statement{Pos: ast.Pos(stmt)}
+			f.Statements = append(f.Statements, s)
+			f.statements[s.Pos] = s
+		}
+	}
+	ast.Inspect(&program, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.Program:
+			addStatements(node.Statements)
+		case *ast.BlockStatement:
+			addStatements(node.Statements)
+		case *ast.IfExpression:
+			b := &Branch{Pos: ast.Pos(node)}
+			f.Branches = append(f.Branches, b)
+			f.branches[b.Pos] = b
+		}
+		return true
+	})
+	sort.Slice(f.Statements, func(i, j int) bool { return before(f.Statements[i].Pos, f.Statements[j].Pos) })
+	sort.Slice(f.Branches, func(i, j int) bool { return before(f.Branches[i].Pos, f.Branches[j].Pos) })
+	return f
+}
+
+func before(a, b token.Position) bool {
+	if a.Line != b.Line {
+		return a.Line < b.Line
+	}
+	return a.Column < b.Column
+}
+
+// StatementsCovered returns how many statements ran at least once.
+func (f *File) StatementsCovered() int {
+	n := 0
+	for _, s := range f.Statements {
+		if s.Count > 0 {
+			n++
+		}
+	}
+	return n
+}
+
+// BranchesCovered returns how many branches ran at least once, out of
+// two for each if expression.
+func (f *File) BranchesCovered() int {
+	n := 0
+	for _, b := range f.Branches {
+		if b.Consequence > 0 {
+			n++
+		}
+		if b.Alternative > 0 {
+			n++
+		}
+	}
+	return n
+}
+
+// StatementPercent is the percentage of statements covered, 100 for a
+// file without any.
+func (f *File) StatementPercent() float64 {
+	return percent(f.StatementsCovered(), len(f.Statements))
+}
+
+func (f *File) BranchPercent() float64 {
+	return percent(f.BranchesCovered(), 2*len(f.Branches))
+}
+
+func percent(covered, total int) float64 {
+	if total == 0 {
+		return 100
+	}
+	return 100 * float64(covered) / float64(total)
+}
+
+// Coverage is an object.Tracer counting the statements and branches
+// run in each file. Files are read the first time one of their
+// statements runs, unless they were added beforehand.
+type Coverage struct {
+	mu    sync.Mutex
+	files map[string]*File
+}
+
+func New() *Coverage {
+	return &Coverage{files: make(map[string]*File)}
+}
+
+// AddFile registers source as the contents of path, so the file is
+// reported even when none of it runs.
+func (c *Coverage) AddFile(path, source string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.files[path] = newFile(path, source)
+}
+
+// Files returns the files seen so far, sorted by path.
+func (c *Coverage) Files() []*File {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	files := make([]*File, 0, len(c.files))
+	for _, f := range c.files {
+		if f != nil {
+			files = append(files, f)
+		}
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+	return files
+}
+
+// file returns the coverage of the file env's code comes from, or nil
+// for code that is not in a readable file. c.mu must be held.
+func (c *Coverage) file(env *object.Environment) *File {
+	src := env.File()
+	if src == nil {
+		return nil
+	}
+	if f, ok := c.files[src.Path]; ok {
+		return f
+	}
+	var f *File
+	if code, err := os.ReadFile(src.Path); err == nil {
+		f = newFile(src.Path, string(code))
+	}
+	c.files[src.Path] = f
+	return f
+}
+
+func (c *Coverage) Statement(stmt ast.Statement, env *object.Environment) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if f := c.file(env); f != nil {
+		if s, ok := f.statements[ast.Pos(stmt)]; ok {
+			s.Count++
+		}
+	}
+}
+
+func (c *Coverage) Branch(ie *ast.IfExpression, consequence bool, env *object.Environment) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if f := c.file(env); f != nil {
+		if b, ok := f.branches[ast.Pos(ie)]; ok {
+			if consequence {
+				b.Consequence++
+			} else {
+				b.Alternative++
+			}
+		}
+	}
+}
+
+func (c *Coverage) Call(fn *object.Function, env *object.Environment) {}
+
+func (c *Coverage) Return(fn *object.Function, result object.Object) {}
+
+// Spawn counts tasks along with the code that started them.
+func (c *Coverage) Spawn() object.Tracer {
+	return c
+}
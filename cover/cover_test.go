@@ -0,0 +1,137 @@
+package cover
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/shozawa/monkey/evaluator"
+	"github.com/shozawa/monkey/lexer"
+	"github.com/shozawa/monkey/object"
+	"github.com/shozawa/monkey/parser"
+)
+
+const testProgram = `let sign = fn(x) {
+    if (x < 0) {
+        return -1;
+    }
+    if (x > 0) { 1 } else { 0 }
+};
+let never = fn() {
+    2
+};
+sign(5);
+sign(3);
+`
+
+func run(t *testing.T, c *Coverage, path, code string) {
+	program := parser.New(lexer.New(code)).Parse()
+	env := object.NewEnvWithRuntime(&object.Runtime{Tracer: c})
+	env.SetFile(&object.File{Path: path})
+	evaluator.Eval(&program, env)
+}
+
+func TestCoverage(t *testing.T) {
+	c := New()
+	c.AddFile("sign.monkey", testProgram)
+	run(t, c, "sign.monkey", testProgram)
+	files := c.Files()
+	if len(files) != 1 {
+		t.Fatalf("files not 1. got=%d", len(files))
+	}
+	f := files[0]
+
+	var counts []int64
+	for _, s := range f.Statements {
+		counts = append(counts, s.Count)
+	}
+	want := []int64{1, 2, 0, 2, 2, 0, 1, 0, 1, 1}
+	if len(counts) != len(want) {
+		t.Fatalf("statement counts not %v. got=%v", want, counts)
+	}
+	for i := range want {
+		if counts[i] != want[i] {
+			t.Errorf("statement counts not %v. got=%v", want, counts)
+			break
+		}
+	}
+	if len(f.Branches) != 2 || *f.Branches[0] != (Branch{f.Branches[0].Pos, 0, 2}) || *f.Branches[1] != (Branch{f.Branches[1].Pos, 2, 0}) {
+		t.Errorf("branches not 0/2 and 2/0. got=%+v %+v", *f.Branches[0], *f.Branches[1])
+	}
+	if f.StatementsCovered() != 7 || f.BranchesCovered() != 2 {
+		t.Errorf("covered not 7 statements and 2 branches. got=%d %d", f.StatementsCovered(), f.BranchesCovered())
+	}
+	if f.BranchPercent() != 50 {
+		t.Errorf("branch percent not 50. got=%f", f.BranchPercent())
+	}
+}
+
+func TestFilesReadOnFirstUse(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "lib.monkey")
+	code := "let x = 1;\nif (x) { 2 }\n"
+	if err := os.WriteFile(path, []byte(code), 0644); err != nil {
+		t.Fatal(err)
+	}
+	c := New()
+	run(t, c, path, code)
+	run(t, c, "", "let y = 2;")
+	files := c.Files()
+	if len(files) != 1 || files[0].Path != path || files[0].StatementPercent() != 100 {
+		t.Errorf("files not %s fully covered. got=%+v", path, files)
+	}
+}
+
+func TestWriteLcov(t *testing.T) {
+	c := New()
+	c.AddFile("sign.monkey", testProgram)
+	run(t, c, "sign.monkey", testProgram)
+	var out bytes.Buffer
+	if err := WriteLcov(&out, c.Files()); err != nil {
+		t.Fatal(err)
+	}
+	want := `TN:
+SF:sign.monkey
+BRDA:2,0,0,-
+BRDA:2,0,1,2
+BRDA:5,1,0,2
+BRDA:5,1,1,-
+BRF:4
+BRH:2
+DA:1,1
+DA:2,2
+DA:3,0
+DA:5,0
+DA:7,1
+DA:8,0
+DA:10,1
+DA:11,1
+LF:8
+LH:5
+end_of_record
+`
+	if out.String() != want {
+		t.Errorf("lcov not\n%s\ngot=\n%s", want, out.String())
+	}
+}
+
+func TestWriteHTML(t *testing.T) {
+	c := New()
+	c.AddFile("sign.monkey", testProgram)
+	run(t, c, "sign.monkey", testProgram)
+	var out bytes.Buffer
+	if err := WriteHTML(&out, c.Files()); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"70.0% of statements, 50.0% of branches",
+		`<span class="uncovered"><span class="number">3</span>        return -1;</span>`,
+		`<span class="partial" title="alternative never ran"><span class="number">5</span>`,
+		`<span class="covered" title="ran 1 times"><span class="number">10</span>sign(5);</span>`,
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("HTML has no %q. got=\n%s", want, out.String())
+		}
+	}
+}
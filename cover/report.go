@@ -0,0 +1,141 @@
+package cover
+
+import (
+	"bufio"
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+)
+
+// WriteLcov writes the coverage of files in the lcov tracefile format,
+// with a DA record per line holding statements and two BRDA records per
+// if expression.
+func WriteLcov(w io.Writer, files []*File) error {
+	bw := bufio.NewWriter(w)
+	for _, f := range files {
+		fmt.Fprintf(bw, "TN:\nSF:%s\n", f.Path)
+		for i, b := range f.Branches {
+			fmt.Fprintf(bw, "BRDA:%d,%d,0,%s\n", b.Pos.Line, i, branchCount(b.Consequence))
+			fmt.Fprintf(bw, "BRDA:%d,%d,1,%s\n", b.Pos.Line, i, branchCount(b.Alternative))
+		}
+		fmt.Fprintf(bw, "BRF:%d\nBRH:%d\n", 2*len(f.Branches), f.BranchesCovered())
+		lines := f.lines()
+		hit := 0
+		for _, l := range lines {
+			fmt.Fprintf(bw, "DA:%d,%d\n", l.number, l.count)
+			if l.count > 0 {
+				hit++
+			}
+		}
+		fmt.Fprintf(bw, "LF:%d\nLH:%d\nend_of_record\n", len(lines), hit)
+	}
+	return bw.Flush()
+}
+
+// branchCount is how lcov writes the count of a branch, "-" meaning
+// that its if expression never ran.
+func branchCount(n int64) string {
+	if n == 0 {
+		return "-"
+	}
+	return fmt.Sprint(n)
+}
+
+type line struct {
+	number int
+	count  int64
+}
+
+// lines returns the lines holding statements, with the count of the
+// least run statement on each.
+func (f *File) lines() []line {
+	var lines []line
+	for _, s := range f.Statements {
+		if n := len(lines); n > 0 && lines[n-1].number == s.Pos.Line {
+			if s.Count < lines[n-1].count {
+				lines[n-1].count = s.Count
+			}
+			continue
+		}
+		lines = append(lines, line{number: s.Pos.Line, count: s.Count})
+	}
+	return lines
+}
+
+type htmlLine struct {
+	Number int
+	Text   string
+	Class  string
+	Title  string
+}
+
+type htmlFile struct {
+	Path             string
+	StatementPercent float64
+	BranchPercent    float64
+	Lines            []htmlLine
+}
+
+var htmlTemplate = template.Must(template.New("cover").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Monkey coverage</title>
+<style>
+body { font-family: sans-serif; }
+pre { font-family: monospace; }
+.covered { background: #dfd; }
+.uncovered { background: #fdd; }
+.partial { background: #ffc; }
+.number { color: #888; display: inline-block; width: 4em; }
+</style>
+</head>
+<body>
+{{range .}}<h2>{{.Path}}</h2>
+<p>{{printf "%.1f" .StatementPercent}}% of statements, {{printf "%.1f" .BranchPercent}}% of branches</p>
+<pre>{{range .Lines}}<span class="{{.Class}}"{{if .Title}} title="{{.Title}}"{{end}}><span class="number">{{.Number}}</span>{{.Text}}</span>
+{{end}}</pre>
+{{end}}</body>
+</html>
+`))
+
+// WriteHTML writes a page showing the source of each file with lines
+// that ran in green and lines that never ran in red. Lines with an if
+// expression that only ever took one branch are yellow.
+func WriteHTML(w io.Writer, files []*File) error {
+	var data []htmlFile
+	for _, f := range files {
+		counts := make(map[int]int64)
+		for _, l := range f.lines() {
+			counts[l.number] = l.count
+		}
+		partial := make(map[int]string)
+		for _, b := range f.Branches {
+			switch {
+			case b.Consequence == 0 && b.Alternative > 0:
+				partial[b.Pos.Line] = "consequence never ran"
+			case b.Alternative == 0 && b.Consequence > 0:
+				partial[b.Pos.Line] = "alternative never ran"
+			}
+		}
+		hf := htmlFile{Path: f.Path, StatementPercent: f.StatementPercent(), BranchPercent: f.BranchPercent()}
+		for i, text := range strings.Split(strings.TrimSuffix(f.Source, "\n"), "\n") {
+			l := htmlLine{Number: i + 1, Text: text}
+			if count, ok := counts[l.Number]; ok {
+				l.Class = "uncovered"
+				if count > 0 {
+					l.Class = "covered"
+					l.Title = fmt.Sprintf("ran %d times", count)
+				}
+			}
+			if title, ok := partial[l.Number]; ok {
+				l.Class = "partial"
+				l.Title = title
+			}
+			hf.Lines = append(hf.Lines, l)
+		}
+		data = append(data, hf)
+	}
+	return htmlTemplate.Execute(w, data)
+}
@@ -0,0 +1,448 @@
+package debugger
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/shozawa/monkey/evaluator"
+	"github.com/shozawa/monkey/lexer"
+	"github.com/shozawa/monkey/object"
+	"github.com/shozawa/monkey/parser"
+	"github.com/shozawa/monkey/prelude"
+)
+
+// DAP_THREAD is the id of the only thread reported: the program's.
+const DAP_THREAD = 1
+
+type dapMessage struct {
+	Seq        int             `json:"seq"`
+	Type       string          `json:"type"`
+	Command    string          `json:"command,omitempty"`
+	Arguments  json.RawMessage `json:"arguments,omitempty"`
+	RequestSeq int             `json:"request_seq,omitempty"`
+	Success    bool            `json:"success,omitempty"`
+	Message    string          `json:"message,omitempty"`
+	Event      string          `json:"event,omitempty"`
+	Body       interface{}     `json:"body,omitempty"`
+}
+
+// DAP is a frontend speaking the Debug Adapter Protocol, for debugging
+// from editors. The program to run comes with the launch request.
+type DAP struct {
+	// NoPrelude is as in interpreter.Config.
+	NoPrelude bool
+
+	debugger *Debugger
+	in       *bufio.Reader
+
+	wmu sync.Mutex
+	out io.Writer
+	seq int
+
+	program     string
+	args        []string
+	stopOnEntry bool
+	resume      chan Command
+
+	mu   sync.Mutex
+	stop *Stop
+	// refs holds the environments handed out as variablesReferences
+	// while stopped; reference n is refs[n-1].
+	refs []*object.Environment
+}
+
+func NewDAP(in io.Reader, out io.Writer) *DAP {
+	a := &DAP{in: bufio.NewReader(in), out: out, resume: make(chan Command, 1)}
+	a.debugger = New(a)
+	return a
+}
+
+type dapHandler func(a *DAP, args json.RawMessage) (interface{}, error)
+
+var dapHandlers map[string]dapHandler
+
+func init() {
+	dapHandlers = map[string]dapHandler{
+		"initialize":             (*DAP).initialize,
+		"launch":                 (*DAP).launch,
+		"setBreakpoints":         (*DAP).setBreakpoints,
+		"setFunctionBreakpoints": (*DAP).setFunctionBreakpoints,
+		"configurationDone":      (*DAP).configurationDone,
+		"threads":                (*DAP).threads,
+		"stackTrace":             (*DAP).stackTrace,
+		"scopes":                 (*DAP).scopes,
+		"variables":              (*DAP).variables,
+		"evaluate":               (*DAP).evaluate,
+		"continue":               resumeWith(CONTINUE),
+		"next":                   resumeWith(STEP_OVER),
+		"stepIn":                 resumeWith(STEP_IN),
+		"stepOut":                resumeWith(STEP_OUT),
+		"pause":                  (*DAP).pause,
+	}
+}
+
+// Serve handles requests until the client disconnects.
+func (a *DAP) Serve() error {
+	for {
+		req, err := a.read()
+		if err != nil {
+			return err
+		}
+		if req.Type != "request" {
+			continue
+		}
+		if req.Command == "disconnect" {
+			a.debugger.Kill()
+			resumeWith(KILL)(a, nil)
+			return a.respond(req, nil, nil)
+		}
+		h, ok := dapHandlers[req.Command]
+		if !ok {
+			a.respond(req, nil, fmt.Errorf("unsupported request %s", req.Command))
+			continue
+		}
+		body, err := h(a, req.Arguments)
+		if err := a.respond(req, body, err); err != nil {
+			return err
+		}
+		// Events that must follow the response.
+		switch {
+		case err != nil:
+		case req.Command == "initialize":
+			a.event("initialized", nil)
+		case req.Command == "configurationDone":
+			go a.run()
+		}
+	}
+}
+
+func (a *DAP) read() (*dapMessage, error) {
+	length := -1
+	for {
+		line, err := a.in.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			break
+		}
+		if name, value, ok := strings.Cut(line, ":"); ok && strings.EqualFold(name, "Content-Length") {
+			if length, err = strconv.Atoi(strings.TrimSpace(value)); err != nil {
+				return nil, fmt.Errorf("bad Content-Length %q", value)
+			}
+		}
+	}
+	if length < 0 {
+		return nil, fmt.Errorf("message without Content-Length")
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(a.in, body); err != nil {
+		return nil, err
+	}
+	var msg dapMessage
+	return &msg, json.Unmarshal(body, &msg)
+}
+
+func (a *DAP) write(msg *dapMessage) error {
+	a.wmu.Lock()
+	defer a.wmu.Unlock()
+	a.seq++
+	msg.Seq = a.seq
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(a.out, "Content-Length: %d\r\n\r\n%s", len(body), body)
+	return err
+}
+
+func (a *DAP) respond(req *dapMessage, body interface{}, err error) error {
+	resp := &dapMessage{Type: "response", RequestSeq: req.Seq, Command: req.Command, Success: err == nil, Body: body}
+	if err != nil {
+		resp.Message = err.Error()
+	}
+	return a.write(resp)
+}
+
+func (a *DAP) event(event string, body interface{}) error {
+	return a.write(&dapMessage{Type: "event", Event: event, Body: body})
+}
+
+func (a *DAP) initialize(args json.RawMessage) (interface{}, error) {
+	return map[string]bool{
+		"supportsConfigurationDoneRequest": true,
+		"supportsFunctionBreakpoints":      true,
+	}, nil
+}
+
+func (a *DAP) launch(args json.RawMessage) (interface{}, error) {
+	var launch struct {
+		Program     string   `json:"program"`
+		Args        []string `json:"args"`
+		StopOnEntry bool     `json:"stopOnEntry"`
+	}
+	if err := json.Unmarshal(args, &launch); err != nil {
+		return nil, err
+	}
+	if launch.Program == "" {
+		return nil, fmt.Errorf("launch needs a program")
+	}
+	a.program, a.args, a.stopOnEntry = launch.Program, launch.Args, launch.StopOnEntry
+	return nil, nil
+}
+
+type dapBreakpoint struct {
+	Verified bool `json:"verified"`
+	Line     int  `json:"line,omitempty"`
+}
+
+// setBreakpoints replaces the line breakpoints. They apply to the
+// launched program only.
+func (a *DAP) setBreakpoints(args json.RawMessage) (interface{}, error) {
+	var set struct {
+		Breakpoints []struct {
+			Line int `json:"line"`
+		} `json:"breakpoints"`
+	}
+	if err := json.Unmarshal(args, &set); err != nil {
+		return nil, err
+	}
+	a.debugger.ClearBreakpoints(false)
+	breakpoints := []dapBreakpoint{}
+	for _, bp := range set.Breakpoints {
+		a.debugger.SetBreakpoint(bp.Line)
+		breakpoints = append(breakpoints, dapBreakpoint{Verified: true, Line: bp.Line})
+	}
+	return map[string]interface{}{"breakpoints": breakpoints}, nil
+}
+
+func (a *DAP) setFunctionBreakpoints(args json.RawMessage) (interface{}, error) {
+	var set struct {
+		Breakpoints []struct {
+			Name string `json:"name"`
+		} `json:"breakpoints"`
+	}
+	if err := json.Unmarshal(args, &set); err != nil {
+		return nil, err
+	}
+	a.debugger.ClearBreakpoints(true)
+	breakpoints := []dapBreakpoint{}
+	for _, bp := range set.Breakpoints {
+		a.debugger.SetFunctionBreakpoint(bp.Name)
+		breakpoints = append(breakpoints, dapBreakpoint{Verified: true})
+	}
+	return map[string]interface{}{"breakpoints": breakpoints}, nil
+}
+
+// configurationDone tells that the client has set its breakpoints, so
+// Serve starts the program.
+func (a *DAP) configurationDone(args json.RawMessage) (interface{}, error) {
+	if a.program == "" {
+		return nil, fmt.Errorf("no program launched")
+	}
+	return nil, nil
+}
+
+func (a *DAP) run() {
+	exitCode := 0
+	defer func() {
+		a.event("exited", map[string]int{"exitCode": exitCode})
+		a.event("terminated", nil)
+	}()
+	stderr := &dapOutput{a, "stderr"}
+	src, err := os.ReadFile(a.program)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		exitCode = 1
+		return
+	}
+	p := parser.New(lexer.New(string(src)))
+	program := p.Parse()
+	if errs := p.Errors(); len(errs) > 0 {
+		for _, msg := range errs {
+			fmt.Fprintf(stderr, "%s:%s\n", a.program, msg)
+		}
+		exitCode = 1
+		return
+	}
+	abs, _ := filepath.Abs(a.program)
+	rt := &object.Runtime{
+		Capabilities: object.CAP_ALL,
+		Stdout:       &dapOutput{a, "stdout"},
+		Stderr:       stderr,
+		Tracer:       a.debugger,
+	}
+	if !a.NoPrelude {
+		rt.Prelude = prelude.Env()
+	}
+	env := object.NewEnvWithRuntime(rt)
+	env.SetFile(&object.File{Path: abs})
+	env.Set(evaluator.ARGS, evaluator.NewArgs(a.args))
+	if result, ok := a.debugger.Run(&program, env, a.stopOnEntry).(*object.Error); ok {
+		fmt.Fprintln(stderr, result.Inspect())
+		exitCode = 1
+	}
+}
+
+// dapOutput forwards the program's output as output events.
+type dapOutput struct {
+	a        *DAP
+	category string
+}
+
+func (o *dapOutput) Write(p []byte) (int, error) {
+	err := o.a.event("output", map[string]string{"category": o.category, "output": string(p)})
+	return len(p), err
+}
+
+func (a *DAP) Stopped(stop *Stop) Command {
+	a.mu.Lock()
+	a.stop = stop
+	a.refs = nil
+	a.mu.Unlock()
+	a.event("stopped", map[string]interface{}{
+		"reason":            stop.Reason,
+		"threadId":          DAP_THREAD,
+		"allThreadsStopped": true,
+	})
+	return <-a.resume
+}
+
+func resumeWith(cmd Command) dapHandler {
+	return func(a *DAP, args json.RawMessage) (interface{}, error) {
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		if a.stop != nil {
+			a.stop = nil
+			a.resume <- cmd
+		}
+		return map[string]bool{"allThreadsContinued": true}, nil
+	}
+}
+
+func (a *DAP) pause(args json.RawMessage) (interface{}, error) {
+	a.debugger.Pause()
+	return nil, nil
+}
+
+func (a *DAP) threads(args json.RawMessage) (interface{}, error) {
+	return map[string]interface{}{
+		"threads": []map[string]interface{}{{"id": DAP_THREAD, "name": "main"}},
+	}, nil
+}
+
+// frame returns the frame with the given id, numbered from 1 for the
+// innermost frame of the current stop.
+func (a *DAP) frame(id int) (Frame, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.stop == nil || id < 1 || id > len(a.stop.Frames) {
+		return Frame{}, fmt.Errorf("no frame %d", id)
+	}
+	return a.stop.Frames[id-1], nil
+}
+
+func (a *DAP) stackTrace(args json.RawMessage) (interface{}, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	frames := []map[string]interface{}{}
+	if a.stop != nil {
+		for i, frame := range a.stop.Frames {
+			frames = append(frames, map[string]interface{}{
+				"id":     i + 1,
+				"name":   frame.Name,
+				"line":   frame.Pos.Line,
+				"column": frame.Pos.Column,
+				"source": map[string]string{"path": a.program},
+			})
+		}
+	}
+	return map[string]interface{}{"stackFrames": frames, "totalFrames": len(frames)}, nil
+}
+
+func (a *DAP) scopes(args json.RawMessage) (interface{}, error) {
+	var req struct {
+		FrameID int `json:"frameId"`
+	}
+	if err := json.Unmarshal(args, &req); err != nil {
+		return nil, err
+	}
+	frame, err := a.frame(req.FrameID)
+	if err != nil {
+		return nil, err
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	scopes := []map[string]interface{}{}
+	for env := frame.Env; env != nil; env = env.Outer() {
+		name := "Closure"
+		switch {
+		case env.Outer() == nil:
+			name = "Globals"
+		case env == frame.Env:
+			name = "Locals"
+		}
+		a.refs = append(a.refs, env)
+		scopes = append(scopes, map[string]interface{}{
+			"name":               name,
+			"variablesReference": len(a.refs),
+			"expensive":          false,
+		})
+	}
+	return map[string]interface{}{"scopes": scopes}, nil
+}
+
+func (a *DAP) variables(args json.RawMessage) (interface{}, error) {
+	var req struct {
+		VariablesReference int `json:"variablesReference"`
+	}
+	if err := json.Unmarshal(args, &req); err != nil {
+		return nil, err
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if req.VariablesReference < 1 || req.VariablesReference > len(a.refs) {
+		return nil, fmt.Errorf("no variables %d", req.VariablesReference)
+	}
+	env := a.refs[req.VariablesReference-1]
+	variables := []map[string]interface{}{}
+	for _, name := range env.LocalNames() {
+		value, _ := env.GetLocal(name)
+		variables = append(variables, map[string]interface{}{
+			"name":               name,
+			"value":              value.Inspect(),
+			"type":               value.Type(),
+			"variablesReference": 0,
+		})
+	}
+	return map[string]interface{}{"variables": variables}, nil
+}
+
+// evaluate looks up a name in a frame, for hovers and watches. Running
+// arbitrary code while stopped is not supported.
+func (a *DAP) evaluate(args json.RawMessage) (interface{}, error) {
+	var req struct {
+		Expression string `json:"expression"`
+		FrameID    int    `json:"frameId"`
+	}
+	if err := json.Unmarshal(args, &req); err != nil {
+		return nil, err
+	}
+	frame, err := a.frame(req.FrameID)
+	if err != nil {
+		return nil, err
+	}
+	value, ok := frame.Env.Get(strings.TrimSpace(req.Expression))
+	if !ok {
+		return nil, fmt.Errorf("%s is not bound", req.Expression)
+	}
+	return map[string]interface{}{"result": value.Inspect(), "variablesReference": 0}, nil
+}
@@ -0,0 +1,289 @@
+package debugger
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/shozawa/monkey/ast"
+	"github.com/shozawa/monkey/evaluator"
+	"github.com/shozawa/monkey/object"
+	"github.com/shozawa/monkey/token"
+)
+
+// Command tells a stopped program how to go on.
+type Command int
+
+const (
+	CONTINUE Command = iota
+	STEP_IN
+	STEP_OVER
+	STEP_OUT
+	KILL
+)
+
+// Reasons a program stops.
+const (
+	REASON_ENTRY      = "entry"
+	REASON_BREAKPOINT = "breakpoint"
+	REASON_STEP       = "step"
+	REASON_PAUSE      = "pause"
+)
+
+// Frame is one call on the stack.
+type Frame struct {
+	// Name is the function's let binding, "main" for the program.
+	Name string
+	Env  *object.Environment
+	// Pos is the statement being executed.
+	Pos token.Position
+}
+
+// Stop describes where a program stopped. Frames are innermost first.
+type Stop struct {
+	Reason string
+	Frames []Frame
+}
+
+// Frontend is the user interface of a debugger. Stopped is called on
+// the evaluating goroutine, which stays stopped until it returns.
+type Frontend interface {
+	Stopped(stop *Stop) Command
+}
+
+// Debugger is an object.Tracer that stops the program at breakpoints
+// and steps. Tasks the program spawns run without stopping, and so does
+// code from other files than the program's, such as the prelude and
+// imported modules, whose lines breakpoints do not refer to.
+type Debugger struct {
+	frontend Frontend
+	file     *object.File
+
+	mu        sync.Mutex
+	lines     map[int]bool
+	functions map[string]bool
+	frames    []*Frame
+	mode      Command
+	// depth is the stack depth when the current step started.
+	depth int
+	// pending stops the program at the next statement, for this reason.
+	pending string
+	// kill ends the program at the next statement.
+	kill bool
+	// last is where the program last stopped, so that a breakpoint on a
+	// line with several statements stops only once.
+	last struct {
+		frame *Frame
+		line  int
+	}
+}
+
+func New(frontend Frontend) *Debugger {
+	return &Debugger{
+		frontend:  frontend,
+		lines:     make(map[int]bool),
+		functions: make(map[string]bool),
+	}
+}
+
+// killed unwinds the evaluation when the frontend answers KILL.
+type killed struct{}
+
+// Run evaluates program in env, which should have the debugger as the
+// tracer of its runtime. With stopOnEntry it stops before the first
+// statement. Run returns nil if the frontend killed the program.
+func (d *Debugger) Run(program *ast.Program, env *object.Environment, stopOnEntry bool) (result object.Object) {
+	d.mu.Lock()
+	d.file = env.File()
+	if stopOnEntry {
+		d.pending = REASON_ENTRY
+	}
+	d.mu.Unlock()
+	defer func() {
+		if r := recover(); r != nil {
+			if _, ok := r.(killed); !ok {
+				panic(r)
+			}
+			result = nil
+		}
+	}()
+	return evaluator.Eval(program, env)
+}
+
+func (d *Debugger) SetBreakpoint(line int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.lines[line] = true
+}
+
+func (d *Debugger) ClearBreakpoint(line int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.lines, line)
+}
+
+// SetFunctionBreakpoint stops the program when a function bound to name
+// is called.
+func (d *Debugger) SetFunctionBreakpoint(name string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.functions[name] = true
+}
+
+func (d *Debugger) ClearFunctionBreakpoint(name string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.functions, name)
+}
+
+// ClearBreakpoints removes every line breakpoint, or every function
+// breakpoint.
+func (d *Debugger) ClearBreakpoints(functions bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if functions {
+		d.functions = make(map[string]bool)
+	} else {
+		d.lines = make(map[int]bool)
+	}
+}
+
+// Breakpoints returns the lines and the function names with breakpoints.
+func (d *Debugger) Breakpoints() ([]int, []string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var lines []int
+	for line := range d.lines {
+		lines = append(lines, line)
+	}
+	var names []string
+	for name := range d.functions {
+		names = append(names, name)
+	}
+	sort.Ints(lines)
+	sort.Strings(names)
+	return lines, names
+}
+
+// Pause stops the program at its next statement. It may be called from
+// any goroutine.
+func (d *Debugger) Pause() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.pending = REASON_PAUSE
+}
+
+// Kill ends the program at its next statement, whether it is running
+// or about to be resumed from a stop. It may be called from any
+// goroutine.
+func (d *Debugger) Kill() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.kill = true
+}
+
+func (d *Debugger) Statement(stmt ast.Statement, env *object.Environment) {
+	d.mu.Lock()
+	if d.kill {
+		d.mu.Unlock()
+		panic(killed{})
+	}
+	if env.File() != d.file {
+		d.mu.Unlock()
+		return
+	}
+	if len(d.frames) == 0 {
+		d.frames = append(d.frames, &Frame{Name: "main", Env: env})
+	}
+	frame := d.frames[len(d.frames)-1]
+	frame.Pos = ast.Pos(stmt)
+	frame.Env = env
+
+	reason := d.pending
+	if reason == "" && d.lines[frame.Pos.Line] && (d.last.frame != frame || d.last.line != frame.Pos.Line) {
+		reason = REASON_BREAKPOINT
+	}
+	if reason == "" {
+		switch depth := len(d.frames); {
+		case d.mode == STEP_IN,
+			d.mode == STEP_OVER && depth <= d.depth,
+			d.mode == STEP_OUT && depth < d.depth:
+			reason = REASON_STEP
+		}
+	}
+	if reason == "" {
+		d.mu.Unlock()
+		return
+	}
+	d.pending = ""
+	d.last.frame, d.last.line = frame, frame.Pos.Line
+	stop := &Stop{Reason: reason, Frames: make([]Frame, len(d.frames))}
+	for i, f := range d.frames {
+		stop.Frames[len(d.frames)-1-i] = *f
+	}
+	d.mu.Unlock()
+
+	cmd := d.frontend.Stopped(stop)
+	if cmd == KILL {
+		panic(killed{})
+	}
+	d.mu.Lock()
+	d.mode = cmd
+	d.depth = len(d.frames)
+	d.mu.Unlock()
+}
+
+func (d *Debugger) Call(fn *object.Function, env *object.Environment) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if fn.Env.File() != d.file {
+		return
+	}
+	name := fn.Name
+	if name == "" {
+		name = "<anonymous>"
+	}
+	d.frames = append(d.frames, &Frame{Name: name, Env: env, Pos: fn.Body.Token.Position()})
+	if fn.Name != "" && d.functions[fn.Name] {
+		d.pending = REASON_BREAKPOINT
+	}
+}
+
+func (d *Debugger) Return(fn *object.Function, result object.Object) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if fn.Env.File() != d.file {
+		return
+	}
+	if len(d.frames) > 0 {
+		d.frames = d.frames[:len(d.frames)-1]
+	}
+}
+
+func (d *Debugger) Branch(ie *ast.IfExpression, consequence bool, env *object.Environment) {}
+
+// Spawn leaves tasks untraced: a stop in a task would interleave with
+// the call stack of the main program.
+func (d *Debugger) Spawn() object.Tracer {
+	return nil
+}
+
+// Scope is one environment in a frame's chain, innermost first.
+type Scope struct {
+	Names  []string
+	Values []object.Object
+}
+
+// Scopes lists the bindings of env and of each environment enclosing it.
+func Scopes(env *object.Environment) []Scope {
+	var scopes []Scope
+	for ; env != nil; env = env.Outer() {
+		var scope Scope
+		for _, name := range env.LocalNames() {
+			value, _ := env.GetLocal(name)
+			scope.Names = append(scope.Names, name)
+			scope.Values = append(scope.Values, value)
+		}
+		scopes = append(scopes, scope)
+	}
+	return scopes
+}
@@ -0,0 +1,330 @@
+package debugger
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shozawa/monkey/ast"
+	"github.com/shozawa/monkey/evaluator"
+	"github.com/shozawa/monkey/lexer"
+	"github.com/shozawa/monkey/object"
+	"github.com/shozawa/monkey/parser"
+)
+
+const testProgram = `let add = fn(a, b) {
+    let sum = a + b;
+    sum
+};
+let x = add(1, 2);
+let y = add(x, 3);
+puts(y);
+`
+
+// script is a frontend answering each stop with the next command.
+type script struct {
+	commands []Command
+	stops    []string
+}
+
+func (s *script) Stopped(stop *Stop) Command {
+	var names []string
+	for _, f := range stop.Frames {
+		names = append(names, f.Name)
+	}
+	s.stops = append(s.stops, fmt.Sprintf("%s %s %s", stop.Frames[0].Pos, strings.Join(names, "<"), stop.Reason))
+	if len(s.commands) == 0 {
+		return CONTINUE
+	}
+	cmd := s.commands[0]
+	s.commands = s.commands[1:]
+	return cmd
+}
+
+func parse(t *testing.T, input string) *ast.Program {
+	p := parser.New(lexer.New(input))
+	program := p.Parse()
+	if errs := p.Errors(); len(errs) > 0 {
+		t.Fatalf("parser errors: %v", errs)
+	}
+	return &program
+}
+
+func debug(t *testing.T, d *Debugger, stopOnEntry bool) (object.Object, string) {
+	var out bytes.Buffer
+	env := object.NewEnvWithRuntime(&object.Runtime{Capabilities: object.CAP_ALL, Stdout: &out, Tracer: d})
+	result := d.Run(parse(t, testProgram), env, stopOnEntry)
+	return result, out.String()
+}
+
+func TestStops(t *testing.T) {
+	tests := []struct {
+		name        string
+		stopOnEntry bool
+		lines       []int
+		functions   []string
+		commands    []Command
+		want        []string
+	}{
+		{"entry", true, nil, nil, nil, []string{"1:1 main entry"}},
+		{"line breakpoint", false, []int{2}, nil, nil, []string{
+			"2:5 add<main breakpoint",
+			"2:5 add<main breakpoint",
+		}},
+		{"function breakpoint", false, nil, []string{"add"}, nil, []string{
+			"2:5 add<main breakpoint",
+			"2:5 add<main breakpoint",
+		}},
+		{"step in", true, nil, nil, []Command{STEP_IN, STEP_IN, STEP_IN, STEP_IN, CONTINUE}, []string{
+			"1:1 main entry",
+			"5:1 main step",
+			"2:5 add<main step",
+			"3:5 add<main step",
+			"6:1 main step",
+		}},
+		{"step over", false, []int{5}, nil, []Command{STEP_OVER, STEP_OVER}, []string{
+			"5:1 main breakpoint",
+			"6:1 main step",
+			"7:1 main step",
+		}},
+		{"step out", false, []int{2}, nil, []Command{STEP_OUT, CONTINUE}, []string{
+			"2:5 add<main breakpoint",
+			"6:1 main step",
+			"2:5 add<main breakpoint",
+		}},
+	}
+	for _, test := range tests {
+		s := &script{commands: test.commands}
+		d := New(s)
+		for _, line := range test.lines {
+			d.SetBreakpoint(line)
+		}
+		for _, name := range test.functions {
+			d.SetFunctionBreakpoint(name)
+		}
+		_, out := debug(t, d, test.stopOnEntry)
+		if strings.Join(s.stops, "\n") != strings.Join(test.want, "\n") {
+			t.Errorf("%s: stops not %q. got=%q", test.name, test.want, s.stops)
+		}
+		if out != "6\n" {
+			t.Errorf("%s: output not %q. got=%q", test.name, "6\n", out)
+		}
+	}
+}
+
+func TestOtherFilesRunWithoutStopping(t *testing.T) {
+	prelude := object.NewEnv()
+	evaluator.Eval(parse(t, "let each = fn(xs, f) {\n    map(xs, f)\n};"), prelude)
+	prelude.Freeze()
+
+	s := &script{commands: []Command{STEP_IN, STEP_IN, STEP_IN}}
+	d := New(s)
+	d.SetBreakpoint(2)
+	d.SetFunctionBreakpoint("each")
+	env := object.NewEnvWithRuntime(&object.Runtime{Prelude: prelude, Tracer: d})
+	env.SetFile(&object.File{Path: "main.monkey"})
+	d.Run(parse(t, "let show = fn(x) {\n    x\n};\neach([1, 2], show);\n"), env, false)
+	want := []string{
+		"2:5 show<main breakpoint",
+		"2:5 show<main breakpoint",
+	}
+	if strings.Join(s.stops, "\n") != strings.Join(want, "\n") {
+		t.Errorf("stops not %q. got=%q", want, s.stops)
+	}
+}
+
+func TestKill(t *testing.T) {
+	d := New(&script{commands: []Command{KILL}})
+	result, out := debug(t, d, true)
+	if result != nil || out != "" {
+		t.Errorf("killed program went on. got result=%v output=%q", result, out)
+	}
+}
+
+func TestKillWhileRunning(t *testing.T) {
+	d := New(&script{})
+	started := make(chan struct{})
+	env := object.NewEnvWithRuntime(&object.Runtime{Tracer: d})
+	env.Set("started", &object.Builtin{Fn: func(env *object.Environment, args ...object.Object) object.Object {
+		close(started)
+		return nil
+	}})
+	program := parse(t, "started();\nlet spin = fn(n) { map(range(1000), fn(x) { x }); spin(n + 1) };\nspin(0);")
+	done := make(chan object.Object)
+	go func() { done <- d.Run(program, env, false) }()
+	<-started
+	d.Kill()
+	select {
+	case result := <-done:
+		if result != nil {
+			t.Errorf("killed program returned %s", result.Inspect())
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("running program was not killed")
+	}
+}
+
+func TestScopes(t *testing.T) {
+	var scopes []Scope
+	d := New(frontendFunc(func(stop *Stop) Command {
+		if scopes == nil {
+			scopes = Scopes(stop.Frames[0].Env)
+		}
+		return CONTINUE
+	}))
+	d.SetBreakpoint(3)
+	debug(t, d, false)
+	if len(scopes) != 2 {
+		t.Fatalf("scopes not 2. got=%d", len(scopes))
+	}
+	var locals []string
+	for i, name := range scopes[0].Names {
+		locals = append(locals, name+"="+scopes[0].Values[i].Inspect())
+	}
+	if strings.Join(locals, " ") != "a=1 b=2 sum=3" {
+		t.Errorf("locals of add not a, b and sum. got=%s", locals)
+	}
+	if fmt.Sprint(scopes[1].Names) != "[add]" {
+		t.Errorf("globals not add. got=%v", scopes[1].Names)
+	}
+}
+
+type frontendFunc func(stop *Stop) Command
+
+func (f frontendFunc) Stopped(stop *Stop) Command { return f(stop) }
+
+func TestTerminal(t *testing.T) {
+	input := strings.Join([]string{"b 3", "c", "bt", "p sum", "env 1", "bogus", "q"}, "\n")
+	var out bytes.Buffer
+	terminal := NewTerminal(strings.NewReader(input), &out, testProgram)
+	env := object.NewEnvWithRuntime(&object.Runtime{Tracer: terminal.Debugger()})
+	terminal.Debugger().Run(parse(t, testProgram), env, true)
+
+	want := `stopped at 1:1 in main (entry)
+>   1 | let add = fn(a, b) {
+(debug) breakpoint at line 3
+(debug) stopped at 3:5 in add (breakpoint)
+>   3 |     sum
+(debug) #0 add at 3:5
+#1 main at 5:1
+(debug) 3
+(debug) scope 0:
+  add = fn(a, b) { ... }
+(debug) unknown command bogus, try help
+(debug) `
+	if out.String() != want {
+		t.Errorf("terminal output not\n%s\ngot=\n%s", want, out.String())
+	}
+}
+
+func TestTerminalSharesInput(t *testing.T) {
+	var out bytes.Buffer
+	in := bufio.NewReader(strings.NewReader("c\nhello\n"))
+	terminal := NewTerminal(in, &out, "puts(read_line());")
+	env := object.NewEnvWithRuntime(&object.Runtime{Capabilities: object.CAP_ALL, Stdin: in, Stdout: &out, Tracer: terminal.Debugger()})
+	terminal.Debugger().Run(parse(t, "puts(read_line());"), env, true)
+	if !strings.HasSuffix(out.String(), "(debug) hello\n") {
+		t.Errorf("program did not read the line after the command. got=%q", out.String())
+	}
+}
+
+func TestDAP(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "main.monkey")
+	if err := os.WriteFile(path, []byte(testProgram), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	clientIn, serverOut := io.Pipe()
+	serverIn, clientOut := io.Pipe()
+	go NewDAP(serverIn, serverOut).Serve()
+	client := &dapClient{t: t, in: bufio.NewReader(clientIn), out: clientOut}
+
+	client.request("initialize", `{}`)
+	client.expect("response", "initialize")
+	client.expect("event", "initialized")
+	client.request("launch", fmt.Sprintf(`{"program":%q}`, path))
+	client.expect("response", "launch")
+	client.request("setBreakpoints", fmt.Sprintf(`{"source":{"path":%q},"breakpoints":[{"line":2}]}`, path))
+	client.expect("response", "setBreakpoints")
+	client.request("configurationDone", `{}`)
+	client.expect("response", "configurationDone")
+	client.expect("event", "stopped")
+
+	client.request("stackTrace", `{"threadId":1}`)
+	if body := client.expect("response", "stackTrace"); !strings.Contains(body, `"line":2,"name":"add"`) {
+		t.Errorf("stack trace not in add at line 2. got=%s", body)
+	}
+	client.request("scopes", `{"frameId":1}`)
+	client.expect("response", "scopes")
+	client.request("variables", `{"variablesReference":1}`)
+	if body := client.expect("response", "variables"); !strings.Contains(body, `"name":"a","type":"INTEGER","value":"1"`) {
+		t.Errorf("variables not a=1 and b=2. got=%s", body)
+	}
+	client.request("evaluate", `{"expression":"b","frameId":1}`)
+	if body := client.expect("response", "evaluate"); !strings.Contains(body, `"result":"2"`) {
+		t.Errorf("evaluate b not 2. got=%s", body)
+	}
+
+	client.request("setBreakpoints", fmt.Sprintf(`{"source":{"path":%q},"breakpoints":[]}`, path))
+	client.expect("response", "setBreakpoints")
+	client.request("continue", `{"threadId":1}`)
+	client.expect("response", "continue")
+	if body := client.expect("event", "output"); !strings.Contains(body, `"output":"6\n"`) {
+		t.Errorf("output not 6. got=%s", body)
+	}
+	client.expect("event", "exited")
+	client.expect("event", "terminated")
+	client.request("disconnect", `{}`)
+	client.expect("response", "disconnect")
+}
+
+type dapClient struct {
+	t   *testing.T
+	in  *bufio.Reader
+	out io.Writer
+	seq int
+}
+
+func (c *dapClient) request(command, args string) {
+	c.seq++
+	msg := fmt.Sprintf(`{"seq":%d,"type":"request","command":%q,"arguments":%s}`, c.seq, command, args)
+	fmt.Fprintf(c.out, "Content-Length: %d\r\n\r\n%s", len(msg), msg)
+}
+
+// expect reads the next message, which must be the given response or
+// event, and returns its body.
+func (c *dapClient) expect(typ, name string) string {
+	c.t.Helper()
+	done := make(chan *dapMessage)
+	var raw json.RawMessage
+	go func() {
+		a := &DAP{in: c.in}
+		msg, err := a.read()
+		if err != nil {
+			done <- nil
+			return
+		}
+		raw, _ = json.Marshal(msg.Body)
+		done <- msg
+	}()
+	select {
+	case msg := <-done:
+		if msg == nil || msg.Type != typ || msg.Command+msg.Event != name {
+			c.t.Fatalf("message not %s %s. got=%+v", typ, name, msg)
+		}
+		if typ == "response" && !msg.Success {
+			c.t.Fatalf("%s failed: %s", name, msg.Message)
+		}
+	case <-time.After(5 * time.Second):
+		c.t.Fatalf("timed out waiting for %s %s", typ, name)
+	}
+	return string(raw)
+}
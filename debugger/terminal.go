@@ -0,0 +1,202 @@
+package debugger
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+const PROMPT = "(debug) "
+
+// LIST_CONTEXT is the number of lines shown either side of the current
+// one by the list command.
+const LIST_CONTEXT = 2
+
+// Terminal is a line-oriented frontend reading commands from in.
+type Terminal struct {
+	debugger *Debugger
+	in       *bufio.Reader
+	out      io.Writer
+	lines    []string
+	stop     *Stop
+}
+
+type terminalCommand struct {
+	names []string
+	usage string
+	help  string
+	// run returns done when the program should resume with the command.
+	run func(t *Terminal, arg string) (cmd Command, done bool)
+}
+
+var terminalCommands []terminalCommand
+
+func init() {
+	resume := func(cmd Command) func(t *Terminal, arg string) (Command, bool) {
+		return func(t *Terminal, arg string) (Command, bool) { return cmd, true }
+	}
+	terminalCommands = []terminalCommand{
+		{[]string{"c", "continue"}, "", "run until the next breakpoint", resume(CONTINUE)},
+		{[]string{"s", "step"}, "", "step into the next statement", resume(STEP_IN)},
+		{[]string{"n", "next"}, "", "step over calls to the next statement", resume(STEP_OVER)},
+		{[]string{"o", "out"}, "", "run until the current function returns", resume(STEP_OUT)},
+		{[]string{"b", "break"}, "[line|function]", "set a breakpoint, or list them", (*Terminal).cmdBreak},
+		{[]string{"clear"}, "<line|function>", "remove a breakpoint", (*Terminal).cmdClear},
+		{[]string{"bt", "backtrace"}, "", "print the call stack", (*Terminal).cmdBacktrace},
+		{[]string{"env"}, "[frame]", "print the environment chain of a frame", (*Terminal).cmdEnv},
+		{[]string{"p", "print"}, "<name>", "print the value bound to name", (*Terminal).cmdPrint},
+		{[]string{"l", "list"}, "", "show the source around the current line", (*Terminal).cmdList},
+		{[]string{"q", "quit"}, "", "stop the program and exit", resume(KILL)},
+		{[]string{"h", "help"}, "", "list the commands", (*Terminal).cmdHelp},
+	}
+}
+
+// NewTerminal returns a frontend for debugging source, along with its
+// debugger.
+func NewTerminal(in io.Reader, out io.Writer, source string) *Terminal {
+	// A *bufio.Reader is used as is, so that the program can share it
+	// and read the lines the terminal leaves.
+	r, ok := in.(*bufio.Reader)
+	if !ok {
+		r = bufio.NewReader(in)
+	}
+	t := &Terminal{in: r, out: out, lines: strings.Split(source, "\n")}
+	t.debugger = New(t)
+	return t
+}
+
+func (t *Terminal) Debugger() *Debugger {
+	return t.debugger
+}
+
+func (t *Terminal) Stopped(stop *Stop) Command {
+	t.stop = stop
+	frame := stop.Frames[0]
+	fmt.Fprintf(t.out, "stopped at %s in %s (%s)\n", frame.Pos, frame.Name, stop.Reason)
+	t.printLine(frame.Pos.Line, true)
+	for {
+		fmt.Fprint(t.out, PROMPT)
+		line, err := t.in.ReadString('\n')
+		if err != nil && line == "" {
+			fmt.Fprintln(t.out)
+			return KILL
+		}
+		name, arg, _ := strings.Cut(strings.TrimSpace(line), " ")
+		if name == "" {
+			continue
+		}
+		cmd, ok := t.lookup(name)
+		if !ok {
+			fmt.Fprintf(t.out, "unknown command %s, try help\n", name)
+			continue
+		}
+		if resume, done := cmd.run(t, strings.TrimSpace(arg)); done {
+			return resume
+		}
+	}
+}
+
+func (t *Terminal) lookup(name string) (terminalCommand, bool) {
+	for _, c := range terminalCommands {
+		for _, n := range c.names {
+			if n == name {
+				return c, true
+			}
+		}
+	}
+	return terminalCommand{}, false
+}
+
+func (t *Terminal) printLine(line int, current bool) {
+	if line < 1 || line > len(t.lines) {
+		return
+	}
+	marker := " "
+	if current {
+		marker = ">"
+	}
+	fmt.Fprintf(t.out, "%s%4d | %s\n", marker, line, t.lines[line-1])
+}
+
+func (t *Terminal) cmdBreak(arg string) (Command, bool) {
+	if arg == "" {
+		lines, names := t.debugger.Breakpoints()
+		for _, line := range lines {
+			fmt.Fprintf(t.out, "line %d\n", line)
+		}
+		for _, name := range names {
+			fmt.Fprintf(t.out, "function %s\n", name)
+		}
+		return 0, false
+	}
+	if line, err := strconv.Atoi(arg); err == nil {
+		t.debugger.SetBreakpoint(line)
+		fmt.Fprintf(t.out, "breakpoint at line %d\n", line)
+	} else {
+		t.debugger.SetFunctionBreakpoint(arg)
+		fmt.Fprintf(t.out, "breakpoint at function %s\n", arg)
+	}
+	return 0, false
+}
+
+func (t *Terminal) cmdClear(arg string) (Command, bool) {
+	if line, err := strconv.Atoi(arg); err == nil {
+		t.debugger.ClearBreakpoint(line)
+	} else {
+		t.debugger.ClearFunctionBreakpoint(arg)
+	}
+	return 0, false
+}
+
+func (t *Terminal) cmdBacktrace(arg string) (Command, bool) {
+	for i, frame := range t.stop.Frames {
+		fmt.Fprintf(t.out, "#%d %s at %s\n", i, frame.Name, frame.Pos)
+	}
+	return 0, false
+}
+
+func (t *Terminal) cmdEnv(arg string) (Command, bool) {
+	n := 0
+	if arg != "" {
+		var err error
+		if n, err = strconv.Atoi(arg); err != nil || n < 0 || n >= len(t.stop.Frames) {
+			fmt.Fprintf(t.out, "no frame %s\n", arg)
+			return 0, false
+		}
+	}
+	for i, scope := range Scopes(t.stop.Frames[n].Env) {
+		fmt.Fprintf(t.out, "scope %d:\n", i)
+		for j, name := range scope.Names {
+			fmt.Fprintf(t.out, "  %s = %s\n", name, scope.Values[j].Inspect())
+		}
+	}
+	return 0, false
+}
+
+func (t *Terminal) cmdPrint(arg string) (Command, bool) {
+	value, ok := t.stop.Frames[0].Env.Get(arg)
+	if !ok {
+		fmt.Fprintf(t.out, "%s is not bound\n", arg)
+		return 0, false
+	}
+	fmt.Fprintln(t.out, value.Inspect())
+	return 0, false
+}
+
+func (t *Terminal) cmdList(arg string) (Command, bool) {
+	current := t.stop.Frames[0].Pos.Line
+	for line := current - LIST_CONTEXT; line <= current+LIST_CONTEXT; line++ {
+		t.printLine(line, line == current)
+	}
+	return 0, false
+}
+
+func (t *Terminal) cmdHelp(arg string) (Command, bool) {
+	for _, c := range terminalCommands {
+		usage := strings.TrimSpace(strings.Join(c.names, ", ") + " " + c.usage)
+		fmt.Fprintf(t.out, "%-28s %s\n", usage, c.help)
+	}
+	return 0, false
+}
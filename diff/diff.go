@@ -0,0 +1,119 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+const CONTEXT = 3
+
+type op struct {
+	kind byte // ' ', '-' or '+'
+	line string
+}
+
+// Unified returns a unified diff turning a into b, or "" when they are
+// equal. The names label the two sides in the header.
+func Unified(aName, bName, a, b string) string {
+	if a == b {
+		return ""
+	}
+	ops := lines(splitLines(a), splitLines(b))
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- %s\n+++ %s\n", aName, bName)
+	for i := 0; i < len(ops); {
+		if ops[i].kind == ' ' {
+			i++
+			continue
+		}
+		// Grow a hunk around this change, merging changes whose context
+		// overlaps.
+		start := max(i-CONTEXT, 0)
+		end := i
+		for j := i; j < len(ops); j++ {
+			if ops[j].kind != ' ' {
+				end = j + 1
+			} else if j-end >= 2*CONTEXT {
+				break
+			}
+		}
+		end = min(end+CONTEXT, len(ops))
+		aStart, bStart := lineNumbers(ops, start)
+		aLen, bLen := 0, 0
+		for _, o := range ops[start:end] {
+			if o.kind != '+' {
+				aLen++
+			}
+			if o.kind != '-' {
+				bLen++
+			}
+		}
+		fmt.Fprintf(&out, "@@ -%d,%d +%d,%d @@\n", aStart, aLen, bStart, bLen)
+		for _, o := range ops[start:end] {
+			fmt.Fprintf(&out, "%c%s\n", o.kind, o.line)
+		}
+		i = end
+	}
+	return out.String()
+}
+
+// lineNumbers returns the 1-based line numbers in a and b of ops[i].
+func lineNumbers(ops []op, i int) (int, int) {
+	a, b := 1, 1
+	for _, o := range ops[:i] {
+		if o.kind != '+' {
+			a++
+		}
+		if o.kind != '-' {
+			b++
+		}
+	}
+	return a, b
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+// lines computes an edit script from the longest common subsequence.
+func lines(a, b []string) []op {
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+	var ops []op
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			ops = append(ops, op{' ', a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			ops = append(ops, op{'-', a[i]})
+			i++
+		default:
+			ops = append(ops, op{'+', b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		ops = append(ops, op{'-', a[i]})
+	}
+	for ; j < len(b); j++ {
+		ops = append(ops, op{'+', b[j]})
+	}
+	return ops
+}
@@ -0,0 +1,30 @@
+package diff
+
+import "testing"
+
+func TestUnified(t *testing.T) {
+	a := "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n"
+	b := "1\n2\nthree\n4\n5\n6\n7\n8\n9\n10\n11\n"
+	want := `--- a
++++ b
+@@ -1,6 +1,6 @@
+ 1
+ 2
+-3
++three
+ 4
+ 5
+ 6
+@@ -8,3 +8,4 @@
+ 8
+ 9
+ 10
++11
+`
+	if got := Unified("a", "b", a, b); got != want {
+		t.Errorf("Unified not %q. got=%q", want, got)
+	}
+	if got := Unified("a", "b", a, a); got != "" {
+		t.Errorf("Unified of equal inputs not empty. got=%q", got)
+	}
+}
@@ -0,0 +1,92 @@
+package evaluator
+
+import (
+	"strings"
+
+	"github.com/shozawa/monkey/diff"
+	"github.com/shozawa/monkey/object"
+)
+
+func init() {
+	builtins["assert"] = &object.Builtin{Fn: assert}
+	builtins["assert_eq"] = &object.Builtin{Fn: assertEq}
+	builtins["assert_error"] = &object.Builtin{Fn: assertError}
+}
+
+// Apply calls fn, a Monkey function or a builtin, with args, as a call
+// expression evaluated in env would.
+func Apply(fn object.Object, args []object.Object, env *object.Environment) object.Object {
+	return applyFunction(fn, args, env)
+}
+
+func assert(env *object.Environment, args ...object.Object) object.Object {
+	if len(args) < 1 || len(args) > 2 {
+		return newError("wrong number of arguments. got=%d, want=1 or 2", len(args))
+	}
+	if isTruthy(orNull(args[0])) {
+		return NULL
+	}
+	if len(args) == 2 {
+		if msg, ok := args[1].(*object.String); ok {
+			return newError("assertion failed: %s", msg.Value)
+		}
+		return newError("assertion failed: %s", args[1].Inspect())
+	}
+	return newError("assertion failed")
+}
+
+// assertEq compares values by type and Inspect output, showing how the
+// output of the value got differs from the one wanted.
+func assertEq(env *object.Environment, args ...object.Object) object.Object {
+	if len(args) != 2 {
+		return newError("wrong number of arguments. got=%d, want=2", len(args))
+	}
+	got, want := orNull(args[0]), orNull(args[1])
+	if got.Type() == want.Type() && got.Inspect() == want.Inspect() {
+		return NULL
+	}
+	d := diff.Unified("want", "got", want.Inspect()+"\n", got.Inspect()+"\n")
+	return newError("assert_eq failed:\n%s", strings.TrimSuffix(d, "\n"))
+}
+
+// assertError calls fn with no arguments and checks that it fails,
+// with a message containing the optional second argument.
+func assertError(env *object.Environment, args ...object.Object) object.Object {
+	if len(args) < 1 || len(args) > 2 {
+		return newError("wrong number of arguments. got=%d, want=1 or 2", len(args))
+	}
+	switch args[0].(type) {
+	case *object.Function, *object.Builtin:
+	default:
+		return newError("argument to 'assert_error' must be FUNCTION, got %s", args[0].Type())
+	}
+	var want string
+	if len(args) == 2 {
+		str, ok := args[1].(*object.String)
+		if !ok {
+			return newError("argument to 'assert_error' must be STRING, got %s", args[1].Type())
+		}
+		want = str.Value
+	}
+	result := applyFunction(args[0], nil, env)
+	errObj, ok := result.(*object.Error)
+	if ok && errObj.Exit {
+		return errObj
+	}
+	if !ok {
+		return newError("assert_error failed: no error, got %s", orNull(result).Inspect())
+	}
+	if !strings.Contains(errObj.Message, want) {
+		return newError("assert_error failed: error %q does not contain %q", errObj.Message, want)
+	}
+	return NULL
+}
+
+// orNull stands NULL in for the nil left by evaluating nothing, such as
+// an empty function body.
+func orNull(obj object.Object) object.Object {
+	if obj == nil {
+		return NULL
+	}
+	return obj
+}
@@ -2,30 +2,56 @@ package evaluator
 
 import (
 	"fmt"
+	"sort"
 
 	"github.com/shozawa/monkey/object"
 )
 
 var builtins = map[string]*object.Builtin{
 	"len": &object.Builtin{
-		Fn: func(args ...object.Object) object.Object {
+		Fn: func(env *object.Environment, args ...object.Object) object.Object {
 			if len(args) != 1 {
 				return newError("wrong number of arguments. got=%d, want=1", len(args))
 			}
 			switch arg := args[0].(type) {
 			case *object.String:
 				return &object.Integer{Value: int64(len(arg.Value))}
+			case *object.Array:
+				return &object.Integer{Value: int64(len(arg.Elements))}
+			case *object.Hash:
+				return &object.Integer{Value: int64(arg.Len())}
 			default:
 				return newError("argument to 'len' not supported, got %s", args[0].Type())
 			}
 		},
 	},
 	"puts": &object.Builtin{
-		Fn: func(args ...object.Object) object.Object {
+		Capabilities: object.CAP_STDOUT,
+		Fn: func(env *object.Environment, args ...object.Object) object.Object {
 			for _, arg := range args {
-				fmt.Println(arg.Inspect())
+				if str, ok := arg.(*object.String); ok {
+					fmt.Fprintln(env.Runtime().Out(), str.Value)
+				} else {
+					fmt.Fprintln(env.Runtime().Out(), arg.Inspect())
+				}
 			}
 			return NULL
 		},
 	},
 }
+
+// IsBuiltin reports whether name is a builtin function.
+func IsBuiltin(name string) bool {
+	_, ok := builtins[name]
+	return ok
+}
+
+// BuiltinNames returns the names of all builtin functions, sorted.
+func BuiltinNames() []string {
+	names := make([]string, 0, len(builtins))
+	for name := range builtins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
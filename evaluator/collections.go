@@ -0,0 +1,225 @@
+package evaluator
+
+import (
+	"sort"
+
+	"github.com/shozawa/monkey/object"
+)
+
+// The callbacks given to these builtins run like any other call, and
+// the first error one returns stops the builtin and is returned from it.
+
+func init() {
+	builtins["map"] = &object.Builtin{Fn: mapArray}
+	builtins["filter"] = &object.Builtin{Fn: filter}
+	builtins["reduce"] = &object.Builtin{Fn: reduce}
+	builtins["sort_by"] = &object.Builtin{Fn: sortBy}
+	builtins["any"] = &object.Builtin{Fn: func(env *object.Environment, args ...object.Object) object.Object {
+		return quantify("any", true, env, args)
+	}}
+	builtins["all"] = &object.Builtin{Fn: func(env *object.Environment, args ...object.Object) object.Object {
+		return quantify("all", false, env, args)
+	}}
+	builtins["zip"] = &object.Builtin{Fn: zip}
+	builtins["range"] = &object.Builtin{Fn: rangeArray}
+}
+
+// callbackArgs checks that args are an array and a function, followed
+// by extra other arguments.
+func callbackArgs(name string, args []object.Object, extra int) (*object.Array, object.Object, *object.Error) {
+	if len(args) != 2+extra {
+		return nil, nil, newError("wrong number of arguments. got=%d, want=%d", len(args), 2+extra)
+	}
+	array, ok := args[0].(*object.Array)
+	if !ok {
+		return nil, nil, newError("first argument to '%s' must be ARRAY, got %s", name, args[0].Type())
+	}
+	switch args[1].(type) {
+	case *object.Function, *object.Builtin:
+	default:
+		return nil, nil, newError("second argument to '%s' must be FUNCTION, got %s", name, args[1].Type())
+	}
+	return array, args[1], nil
+}
+
+func call(fn object.Object, env *object.Environment, args ...object.Object) object.Object {
+	return orNull(applyFunction(fn, args, env))
+}
+
+func mapArray(env *object.Environment, args ...object.Object) object.Object {
+	array, fn, errObj := callbackArgs("map", args, 0)
+	if errObj != nil {
+		return errObj
+	}
+	elements := make([]object.Object, len(array.Elements))
+	for i, e := range array.Elements {
+		result := call(fn, env, e)
+		if isError(result) {
+			return result
+		}
+		elements[i] = result
+	}
+	return &object.Array{Elements: elements}
+}
+
+func filter(env *object.Environment, args ...object.Object) object.Object {
+	array, fn, errObj := callbackArgs("filter", args, 0)
+	if errObj != nil {
+		return errObj
+	}
+	elements := []object.Object{}
+	for _, e := range array.Elements {
+		result := call(fn, env, e)
+		if isError(result) {
+			return result
+		}
+		if isTruthy(result) {
+			elements = append(elements, e)
+		}
+	}
+	return &object.Array{Elements: elements}
+}
+
+// reduce(array, fn, initial) folds the array into
+// fn(fn(initial, first), second) and so on.
+func reduce(env *object.Environment, args ...object.Object) object.Object {
+	array, fn, errObj := callbackArgs("reduce", args, 1)
+	if errObj != nil {
+		return errObj
+	}
+	acc := args[2]
+	for _, e := range array.Elements {
+		acc = call(fn, env, acc, e)
+		if isError(acc) {
+			return acc
+		}
+	}
+	return acc
+}
+
+// sortBy returns the elements ordered by the keys fn gives them, which
+// must be all integers or all strings. Elements with equal keys keep
+// their order.
+func sortBy(env *object.Environment, args ...object.Object) object.Object {
+	array, fn, errObj := callbackArgs("sort_by", args, 0)
+	if errObj != nil {
+		return errObj
+	}
+	keys := make([]object.Object, len(array.Elements))
+	for i, e := range array.Elements {
+		key := call(fn, env, e)
+		if isError(key) {
+			return key
+		}
+		switch {
+		case i == 0 && !isInteger(key) && key.Type() != object.STRING_OBJ:
+			return newError("sort_by: key %s of element 0 is %s, keys must be INTEGER or STRING", key.Inspect(), key.Type())
+		case i == 0:
+		case isInteger(key) && isInteger(keys[0]):
+		case key.Type() == object.STRING_OBJ && keys[0].Type() == object.STRING_OBJ:
+		default:
+			return newError("sort_by: key %s of element %d cannot be compared with key %s", key.Inspect(), i, keys[0].Inspect())
+		}
+		keys[i] = key
+	}
+	order := make([]int, len(keys))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		a, b := keys[order[i]], keys[order[j]]
+		if a, ok := a.(*object.String); ok {
+			return a.Value < b.(*object.String).Value
+		}
+		return toBig(a).Cmp(toBig(b)) < 0
+	})
+	elements := make([]object.Object, len(order))
+	for i, j := range order {
+		elements[i] = array.Elements[j]
+	}
+	return &object.Array{Elements: elements}
+}
+
+// quantify reports whether fn is truthy for any element when want is
+// true, or for all of them when it is false, stopping at the first
+// element that settles the answer.
+func quantify(name string, want bool, env *object.Environment, args []object.Object) object.Object {
+	array, fn, errObj := callbackArgs(name, args, 0)
+	if errObj != nil {
+		return errObj
+	}
+	for _, e := range array.Elements {
+		result := call(fn, env, e)
+		if isError(result) {
+			return result
+		}
+		if isTruthy(result) == want {
+			return nativeToBoolObject(want)
+		}
+	}
+	return nativeToBoolObject(!want)
+}
+
+// zip pairs up the elements of arrays at the same index, stopping with
+// the shortest array.
+func zip(env *object.Environment, args ...object.Object) object.Object {
+	if len(args) == 0 {
+		return newError("wrong number of arguments. got=0, want=1 or more")
+	}
+	arrays := make([]*object.Array, len(args))
+	n := -1
+	for i, arg := range args {
+		array, ok := arg.(*object.Array)
+		if !ok {
+			return newError("argument to 'zip' must be ARRAY, got %s", arg.Type())
+		}
+		arrays[i] = array
+		if n < 0 || len(array.Elements) < n {
+			n = len(array.Elements)
+		}
+	}
+	elements := make([]object.Object, n)
+	for i := range elements {
+		tuple := make([]object.Object, len(arrays))
+		for j, array := range arrays {
+			tuple[j] = array.Elements[i]
+		}
+		elements[i] = &object.Array{Elements: tuple}
+	}
+	return &object.Array{Elements: elements}
+}
+
+// rangeArray returns the integers from start up to but not including
+// end, stepping by step. range(end) starts at 0, and the step is 1
+// unless given.
+func rangeArray(env *object.Environment, args ...object.Object) object.Object {
+	if len(args) < 1 || len(args) > 3 {
+		return newError("wrong number of arguments. got=%d, want=1 to 3", len(args))
+	}
+	values := make([]int64, len(args))
+	for i, arg := range args {
+		integer, ok := arg.(*object.Integer)
+		if !ok {
+			return newError("argument to 'range' must be INTEGER, got %s", arg.Type())
+		}
+		values[i] = integer.Value
+	}
+	start, end, step := int64(0), values[0], int64(1)
+	if len(values) > 1 {
+		start, end = values[0], values[1]
+	}
+	if len(values) > 2 {
+		step = values[2]
+	}
+	if step == 0 {
+		return newError("step given to 'range' must not be 0")
+	}
+	elements := []object.Object{}
+	for i := start; step > 0 && i < end || step < 0 && i > end; i += step {
+		elements = append(elements, &object.Integer{Value: i})
+		if i+step < i != (step < 0) {
+			break // i + step overflowed
+		}
+	}
+	return &object.Array{Elements: elements}
+}
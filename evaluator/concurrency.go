@@ -0,0 +1,167 @@
+package evaluator
+
+import (
+	"reflect"
+
+	"github.com/shozawa/monkey/object"
+)
+
+// Tasks run in their own goroutines but share whatever environments
+// their functions closed over. Those environments are locked
+// internally, so reading and rebinding a captured name from several
+// tasks is safe, but nothing orders the tasks against each other:
+// coordinate through channels or await instead of shared bindings.
+// Bindings made inside a task's function land in that call's own
+// environment and are never visible to other tasks.
+//
+// Nothing detects tasks that wait on each other forever. A recv, send,
+// select or await that no other task will ever complete blocks its task
+// for good; when that task is the program itself and no other goroutine
+// of the host is running, Go stops the whole process with "all
+// goroutines are asleep - deadlock!". Hosts that must survive such
+// programs should evaluate them in a goroutine they can abandon.
+
+func init() {
+	builtins["spawn"] = &object.Builtin{Fn: spawn}
+	builtins["await"] = &object.Builtin{Fn: await}
+	builtins["channel"] = &object.Builtin{Fn: newChannel}
+	builtins["send"] = &object.Builtin{Fn: send}
+	builtins["recv"] = &object.Builtin{Fn: recv}
+	builtins["close"] = &object.Builtin{Fn: closeChannel}
+	builtins["select"] = &object.Builtin{Fn: selectChannel}
+}
+
+func spawn(env *object.Environment, args ...object.Object) object.Object {
+	if len(args) < 1 {
+		return newError("wrong number of arguments. got=%d, want>=1", len(args))
+	}
+	fn := args[0]
+	switch fn.(type) {
+	case *object.Function, *object.Builtin:
+	default:
+		return newError("argument to 'spawn' must be FUNCTION, got %s", fn.Type())
+	}
+	task := object.NewTask()
+	taskEnv := object.NewEnclosedEnvironment(env)
+	taskEnv.SetRuntime(env.Runtime().Spawned())
+	go func() {
+		var result object.Object
+		defer func() {
+			if r := recover(); r != nil {
+				result = newError("task panicked: %v", r)
+			}
+			task.Resolve(result)
+		}()
+		result = applyFunction(fn, args[1:], taskEnv)
+	}()
+	return task
+}
+
+func await(env *object.Environment, args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+	task, ok := args[0].(*object.Task)
+	if !ok {
+		return newError("argument to 'await' must be TASK, got %s", args[0].Type())
+	}
+	if result := task.Wait(); result != nil {
+		return result
+	}
+	return NULL
+}
+
+func newChannel(env *object.Environment, args ...object.Object) object.Object {
+	if len(args) > 1 {
+		return newError("wrong number of arguments. got=%d, want=0 or 1", len(args))
+	}
+	size := int64(0)
+	if len(args) == 1 {
+		integer, ok := args[0].(*object.Integer)
+		if !ok {
+			return newError("argument to 'channel' must be INTEGER, got %s", args[0].Type())
+		}
+		if integer.Value < 0 {
+			return newError("channel size must not be negative, got %d", integer.Value)
+		}
+		size = integer.Value
+	}
+	return &object.Channel{Ch: make(chan object.Object, size)}
+}
+
+func send(env *object.Environment, args ...object.Object) (result object.Object) {
+	if len(args) != 2 {
+		return newError("wrong number of arguments. got=%d, want=2", len(args))
+	}
+	ch, ok := args[0].(*object.Channel)
+	if !ok {
+		return newError("first argument to 'send' must be CHANNEL, got %s", args[0].Type())
+	}
+	defer func() {
+		if recover() != nil {
+			result = newError("send on closed channel")
+		}
+	}()
+	ch.Ch <- args[1]
+	return NULL
+}
+
+// recv returns the next value sent on the channel, or null once it is
+// closed and drained. It blocks until then, for good if no task ever
+// sends or closes.
+func recv(env *object.Environment, args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+	ch, ok := args[0].(*object.Channel)
+	if !ok {
+		return newError("argument to 'recv' must be CHANNEL, got %s", args[0].Type())
+	}
+	if value, ok := <-ch.Ch; ok {
+		return value
+	}
+	return NULL
+}
+
+func closeChannel(env *object.Environment, args ...object.Object) (result object.Object) {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+	ch, ok := args[0].(*object.Channel)
+	if !ok {
+		return newError("argument to 'close' must be CHANNEL, got %s", args[0].Type())
+	}
+	defer func() {
+		if recover() != nil {
+			result = newError("close of closed channel")
+		}
+	}()
+	close(ch.Ch)
+	return NULL
+}
+
+// selectChannel blocks until one of the given channels delivers a value
+// and returns it. Closed channels are dropped from the selection; once
+// all of them are closed it returns null.
+func selectChannel(env *object.Environment, args ...object.Object) object.Object {
+	if len(args) == 0 {
+		return newError("wrong number of arguments. got=0, want>=1")
+	}
+	cases := make([]reflect.SelectCase, len(args))
+	for i, arg := range args {
+		ch, ok := arg.(*object.Channel)
+		if !ok {
+			return newError("argument %d to 'select' must be CHANNEL, got %s", i, arg.Type())
+		}
+		cases[i] = reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ch.Ch)}
+	}
+	for open := len(cases); open > 0; {
+		chosen, value, ok := reflect.Select(cases)
+		if ok {
+			return value.Interface().(object.Object)
+		}
+		cases[chosen].Chan = reflect.Value{}
+		open--
+	}
+	return NULL
+}
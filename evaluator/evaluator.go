@@ -2,11 +2,15 @@ package evaluator
 
 import (
 	"fmt"
+	"math"
+	"math/big"
 
 	"github.com/shozawa/monkey/ast"
 	"github.com/shozawa/monkey/object"
 )
 
+// TRUE, FALSE, NULL and the builtins table are never mutated, so
+// they can be shared by evaluations running in parallel.
 var (
 	TRUE  = &object.Bool{Value: true}
 	FALSE = &object.Bool{Value: false}
@@ -20,9 +24,14 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 	case *ast.LetStatement:
 		val := Eval(node.Value, env)
 		if isError(val) {
-			return nil
+			return val
+		}
+		if _, ok := node.Value.(*ast.FunctionLiteral); ok {
+			val.(*object.Function).Name = node.Name.Value
+		}
+		if err := env.Set(node.Name.Value, val); err != nil {
+			return newError("cannot bind %s: %s", node.Name.Value, err)
 		}
-		env.Set(node.Name.Value, val)
 		return nil
 	case *ast.ReturnStatement:
 		val := Eval(node.ReturnValue, env)
@@ -42,8 +51,12 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		return newError("identifier not found: " + node.Value)
 	case *ast.IntegerLiteral:
 		return &object.Integer{Value: node.Value}
+	case *ast.BigIntegerLiteral:
+		return &object.BigInteger{Value: node.Value}
 	case *ast.StringLiteral:
 		return &object.String{Value: node.Value}
+	case *ast.RegexLiteral:
+		return &object.Regex{Value: node.Regexp}
 	case *ast.BoolLiteral:
 		return strToBoolObject(node.Value)
 	case *ast.BlockStatement:
@@ -56,8 +69,34 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		return &object.Function{Parameters: params, Body: body, Env: env}
 	case *ast.CallExpression:
 		function := Eval(node.Function, env)
+		if isError(function) {
+			return function
+		}
 		args := evalExpressions(node.Arguments, env)
-		return applyFunction(function, args)
+		if len(args) == 1 && isError(args[0]) {
+			return args[0]
+		}
+		return applyFunction(function, args, env)
+	case *ast.MemberExpression:
+		return evalMemberExpression(node, env)
+	case *ast.ArrayLiteral:
+		elements := evalExpressions(node.Elements, env)
+		if len(elements) == 1 && isError(elements[0]) {
+			return elements[0]
+		}
+		return &object.Array{Elements: elements}
+	case *ast.HashLiteral:
+		return evalHashLiteral(node, env)
+	case *ast.IndexExpression:
+		left := Eval(node.Left, env)
+		if isError(left) {
+			return left
+		}
+		index := Eval(node.Index, env)
+		if isError(index) {
+			return index
+		}
+		return evalIndexExpression(left, index)
 	case *ast.Infix:
 		left := Eval(node.Left, env)
 		if isError(left) {
@@ -81,7 +120,11 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 func evalProgram(stmts []ast.Statement, env *object.Environment) object.Object {
 	var result object.Object
 
+	tracer := env.Runtime().Tracer
 	for _, stmt := range stmts {
+		if tracer != nil {
+			tracer.Statement(stmt, env)
+		}
 		result = Eval(stmt, env)
 		switch result := result.(type) {
 		case *object.ReturnValue:
@@ -97,7 +140,11 @@ func evalProgram(stmts []ast.Statement, env *object.Environment) object.Object {
 func evalBlockStatement(block *ast.BlockStatement, env *object.Environment) object.Object {
 	var result object.Object
 
+	tracer := env.Runtime().Tracer
 	for _, stmt := range block.Statements {
+		if tracer != nil {
+			tracer.Statement(stmt, env)
+		}
 		result = Eval(stmt, env)
 		if result != nil {
 			t := result.Type()
@@ -110,14 +157,18 @@ func evalBlockStatement(block *ast.BlockStatement, env *object.Environment) obje
 	return result
 }
 
+// evalExpressions evaluates exps in order. When one of them fails, the
+// result is just its error.
 func evalExpressions(
 	exps []ast.Expression,
 	env *object.Environment,
 ) []object.Object {
-	// TODO: Error hadnling
 	var result []object.Object
 	for _, exp := range exps {
 		evaluated := Eval(exp, env)
+		if isError(evaluated) {
+			return []object.Object{evaluated}
+		}
 		result = append(result, evaluated)
 	}
 	return result
@@ -138,11 +189,17 @@ func evalPrefixExpression(
 }
 
 func evalMinusOperator(right object.Object) object.Object {
-	integer, ok := right.(*object.Integer)
-	if !ok {
+	switch right := right.(type) {
+	case *object.Integer:
+		if right.Value == math.MinInt64 {
+			return newInteger(new(big.Int).Neg(big.NewInt(right.Value)))
+		}
+		return &object.Integer{Value: -right.Value}
+	case *object.BigInteger:
+		return newInteger(new(big.Int).Neg(right.Value))
+	default:
 		return newError("%s is not INTEGER", right)
 	}
-	return &object.Integer{Value: -integer.Value}
 }
 
 func evalBangOperator(right object.Object) object.Object {
@@ -165,6 +222,8 @@ func evalInfixExpression(
 	switch {
 	case left.Type() == object.INTEGER_OBJ && right.Type() == object.INTEGER_OBJ:
 		return evalIntegerInfixExpression(operator, left, right)
+	case isInteger(left) && isInteger(right):
+		return evalBigIntegerInfixExpression(operator, toBig(left), toBig(right))
 	case left.Type() != right.Type():
 		return newError("type mismatch: %s %s %s", left.Type(), operator, right.Type())
 	default:
@@ -178,16 +237,38 @@ func evalIntegerInfixExpression(
 ) object.Object {
 	leftVal := left.(*object.Integer).Value
 	rightVal := right.(*object.Integer).Value
+	// Results that overflow are computed again as big integers.
 	switch operator {
 	case "+":
-		return &object.Integer{Value: leftVal + rightVal}
+		sum := leftVal + rightVal
+		if (leftVal^sum)&(rightVal^sum) < 0 {
+			return evalBigIntegerInfixExpression(operator, toBig(left), toBig(right))
+		}
+		return &object.Integer{Value: sum}
 	case "-":
-		return &object.Integer{Value: leftVal - rightVal}
+		difference := leftVal - rightVal
+		if (leftVal^rightVal)&(leftVal^difference) < 0 {
+			return evalBigIntegerInfixExpression(operator, toBig(left), toBig(right))
+		}
+		return &object.Integer{Value: difference}
 	case "*":
-		return &object.Integer{Value: leftVal * rightVal}
+		product := leftVal * rightVal
+		if leftVal != 0 && (product/leftVal != rightVal || leftVal == -1 && rightVal == math.MinInt64) {
+			return evalBigIntegerInfixExpression(operator, toBig(left), toBig(right))
+		}
+		return &object.Integer{Value: product}
 	case "/":
+		if rightVal == 0 {
+			return newError("division by zero: %d / 0", leftVal)
+		}
+		if leftVal == math.MinInt64 && rightVal == -1 {
+			return evalBigIntegerInfixExpression(operator, toBig(left), toBig(right))
+		}
 		return &object.Integer{Value: leftVal / rightVal}
 	case "%":
+		if rightVal == 0 {
+			return newError("division by zero: %d %% 0", leftVal)
+		}
 		return &object.Integer{Value: leftVal % rightVal}
 	case "==":
 		return nativeToBoolObject(leftVal == rightVal)
@@ -202,12 +283,61 @@ func evalIntegerInfixExpression(
 	}
 }
 
+func evalHashLiteral(node *ast.HashLiteral, env *object.Environment) object.Object {
+	hash := object.NewHash()
+	for _, pair := range node.Pairs {
+		key := Eval(pair.Key, env)
+		if isError(key) {
+			return key
+		}
+		hashable, ok := key.(object.Hashable)
+		if !ok {
+			return newError("unusable as hash key: %s", key.Type())
+		}
+		value := Eval(pair.Value, env)
+		if isError(value) {
+			return value
+		}
+		hash.Set(hashable, value)
+	}
+	return hash
+}
+
+func evalIndexExpression(left, index object.Object) object.Object {
+	if hash, ok := left.(*object.Hash); ok {
+		key, ok := index.(object.Hashable)
+		if !ok {
+			return newError("unusable as hash key: %s", index.Type())
+		}
+		if value, ok := hash.Get(key); ok {
+			return value
+		}
+		return NULL
+	}
+	array, ok := left.(*object.Array)
+	if !ok {
+		return newError("index operator not supported: %s", left.Type())
+	}
+	i, ok := index.(*object.Integer)
+	if !ok {
+		return newError("array index must be INTEGER, got %s", index.Type())
+	}
+	if i.Value < 0 || i.Value >= int64(len(array.Elements)) {
+		return NULL
+	}
+	return array.Elements[i.Value]
+}
+
 func evalIfExpression(ie *ast.IfExpression, env *object.Environment) object.Object {
 	condition := Eval(ie.Condition, env)
 	if isError(condition) {
 		return condition
 	}
-	if isTruthy(condition) {
+	truthy := isTruthy(condition)
+	if tracer := env.Runtime().Tracer; tracer != nil {
+		tracer.Branch(ie, truthy, env)
+	}
+	if truthy {
 		return Eval(ie.Consequence, env)
 	} else if ie.Alternative != nil {
 		return Eval(ie.Alternative, env)
@@ -216,14 +346,28 @@ func evalIfExpression(ie *ast.IfExpression, env *object.Environment) object.Obje
 	}
 }
 
-func applyFunction(fn object.Object, args []object.Object) object.Object {
+func applyFunction(fn object.Object, args []object.Object, env *object.Environment) object.Object {
 	switch function := fn.(type) {
 	case *object.Function:
-		extendEnv := extendFunctionEnv(function, args)
-		evaluated := Eval(function.Body, extendEnv)
-		return unwrapReturnValue(evaluated)
+		if len(args) != len(function.Parameters) {
+			return newError("wrong number of arguments: want=%d, got=%d", len(function.Parameters), len(args))
+		}
+		extendEnv := extendFunctionEnv(function, args, env.Runtime())
+		tracer := env.Runtime().Tracer
+		if tracer != nil {
+			tracer.Call(function, extendEnv)
+		}
+		result := unwrapReturnValue(Eval(function.Body, extendEnv))
+		if tracer != nil {
+			tracer.Return(function, result)
+		}
+		return result
 	case *object.Builtin:
-		return function.Fn(args...)
+		if rt := env.Runtime(); !rt.Granted(function.Capabilities) {
+			missing := function.Capabilities &^ rt.Capabilities
+			return newError("permission denied: %s capability not granted", missing)
+		}
+		return function.Fn(env, args...)
 	default:
 		return newError("not a function: %s", function.Type())
 	}
@@ -240,8 +384,12 @@ func unwrapReturnValue(obj object.Object) object.Object {
 func extendFunctionEnv(
 	fn *object.Function,
 	args []object.Object,
+	rt *object.Runtime,
 ) *object.Environment {
+	// The callee runs with the caller's capabilities, not those of the
+	// environment it was defined in.
 	env := object.NewEnclosedEnvironment(fn.Env)
+	env.SetRuntime(rt)
 
 	for paramIdx, param := range fn.Parameters {
 		env.Set(param.Value, args[paramIdx])
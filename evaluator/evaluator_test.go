@@ -1,8 +1,16 @@
 package evaluator
 
 import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/shozawa/monkey/ast"
 	"github.com/shozawa/monkey/lexer"
 	"github.com/shozawa/monkey/object"
 	"github.com/shozawa/monkey/parser"
@@ -58,6 +66,7 @@ func TestEvalBuiltinFunction(t *testing.T) {
 	}{
 		{`len("");`, 0},
 		{`len("four");`, 4},
+		{`len([1, 2, 3]);`, 3},
 	}
 	for _, test := range tests {
 		evaluated := testEval(test.input)
@@ -65,6 +74,422 @@ func TestEvalBuiltinFunction(t *testing.T) {
 	}
 }
 
+func TestBuiltinCapabilities(t *testing.T) {
+	tests := []struct {
+		input   string
+		granted object.Capability
+		want    string
+	}{
+		{`puts("hi")`, object.CAP_NONE, "permission denied: stdout capability not granted"},
+		{`let say = fn(s) { puts(s) }; say("hi")`, object.CAP_NONE, "permission denied: stdout capability not granted"},
+		{`puts("hi")`, object.CAP_CLOCK, "permission denied: stdout capability not granted"},
+		{`now()`, object.CAP_STDOUT, "permission denied: clock capability not granted"},
+		{`random(2)`, object.CAP_CLOCK, "permission denied: random capability not granted"},
+		{`puts("hi")`, object.CAP_STDOUT, ""},
+		{`len("hi")`, object.CAP_NONE, ""},
+		{`read_line()`, object.CAP_STDOUT, "permission denied: stdin capability not granted"},
+		{`write_file("x", "y")`, object.CAP_FS_READ, "permission denied: fs-write capability not granted"},
+	}
+	for _, test := range tests {
+		l := lexer.New(test.input)
+		p := parser.New(l)
+		program := p.Parse()
+		env := object.NewEnvWithRuntime(&object.Runtime{
+			Capabilities: test.granted,
+			Stdout:       new(bytes.Buffer),
+		})
+		evaluated := Eval(&program, env)
+		errObj, isErr := evaluated.(*object.Error)
+		if test.want == "" {
+			if isErr {
+				t.Errorf("unexpected error for %q: %s", test.input, errObj.Message)
+			}
+			continue
+		}
+		if !isErr {
+			t.Errorf("no error object returned for %q. got=%T(%+v)", test.input, evaluated, evaluated)
+			continue
+		}
+		if errObj.Message != test.want {
+			t.Errorf("wrong error message. expected=%q, got=%q", test.want, errObj.Message)
+		}
+	}
+}
+
+func TestPutsWritesToRuntimeStdout(t *testing.T) {
+	input := `puts("hello"); puts(1 + 2);`
+	var out bytes.Buffer
+	l := lexer.New(input)
+	p := parser.New(l)
+	program := p.Parse()
+	env := object.NewEnvWithRuntime(&object.Runtime{
+		Capabilities: object.CAP_STDOUT,
+		Stdout:       &out,
+	})
+	Eval(&program, env)
+	if got, want := out.String(), "hello\n3\n"; got != want {
+		t.Errorf("output not %q. got=%q", want, got)
+	}
+}
+
+func TestArrays(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"[1, 2 * 2, 3]", "[1, 4, 3]"},
+		{"[]", "[]"},
+		{"[1, 2, 3][0]", "1"},
+		{"let xs = [1, [2, 3]]; xs[1][1 + 0]", "3"},
+		{"[1][1]", "null"},
+		{"[1][-1]", "null"},
+		{"[1][true]", "ERROR: array index must be INTEGER, got BOOLEAN"},
+		{"1[0]", "ERROR: index operator not supported: INTEGER"},
+		{"[1, 1 + true]", "ERROR: type mismatch: INTEGER + BOOLEAN"},
+	}
+	for _, test := range tests {
+		if got := testEval(test.input).Inspect(); got != test.want {
+			t.Errorf("%s: result not %s. got=%s", test.input, test.want, got)
+		}
+	}
+}
+
+func TestHashes(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{`{"b": 1, "a": 2 * 2, true: [], 3: "c"}`, `{"b": 1, "a": 4, true: [], 3: "c"}`},
+		{`{"a": 1, "a": 2}`, `{"a": 2}`},
+		{`let key = "k"; {key: 1}["k"]`, "1"},
+		{`{1: "one"}[1]`, `"one"`},
+		{`{true: 1}[false]`, "null"},
+		{`len({"a": 1, "b": 2})`, "2"},
+		{`{fn() {}: 1}`, "ERROR: unusable as hash key: FUNCTION"},
+		{`{"a": 1}[[]]`, "ERROR: unusable as hash key: ARRAY"},
+	}
+	for _, test := range tests {
+		if got := testEval(test.input).Inspect(); got != test.want {
+			t.Errorf("%s: result not %s. got=%s", test.input, test.want, got)
+		}
+	}
+}
+
+func TestJSON(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{`json_parse("{\"name\": \"monkey\", \"tags\": [1, -2, true, null], \"nested\": {}}")`,
+			`{"name": "monkey", "tags": [1, -2, true, null], "nested": {}}`},
+		{`json_parse(" \"a\\u00e9\\n\" ")`, `"aé\n"`},
+		{`json_parse("[1, 2")`, "ERROR: json_parse: unexpected end of input, want ',' at offset 5"},
+		{`json_parse("{\"a\" 1}")`, "ERROR: json_parse: unexpected '1', want ':' at offset 5"},
+		{`json_parse("[1.5]")`, "ERROR: json_parse: number 1.5 is not an integer at offset 1"},
+		{`json_parse("99999999999999999999")`, "99999999999999999999"},
+		{`json_parse(json_stringify(9223372036854775807 + 1)) == 9223372036854775808`, "true"},
+		{`json_parse("[0, -0, -12]")`, "[0, 0, -12]"},
+		{`json_parse("01")`, "ERROR: json_parse: invalid number 01 at offset 0"},
+		{`json_parse("-")`, "ERROR: json_parse: invalid number - at offset 0"},
+		{`json_parse("1-2")`, "ERROR: json_parse: invalid number 1-2 at offset 0"},
+		{`json_parse("[nul]")`, "ERROR: json_parse: unexpected 'n' at offset 1"},
+		{`json_parse("{} x")`, "ERROR: json_parse: unexpected 'x' after value at offset 3"},
+		{`json_parse("\"abc")`, "ERROR: json_parse: unterminated string at offset 0"},
+		{`json_stringify({"a": [1, "x\"y"], "b": {}, "c": if (false) { 1 }})`, `"{\"a\":[1,\"x\\\"y\"],\"b\":{},\"c\":null}"`},
+		{`json_stringify([1, {"a": true}], 2)`, `"[\n  1,\n  {\n    \"a\": true\n  }\n]"`},
+		{`json_stringify(json_parse("[\"\\t\"]"))`, `"[\"\\t\"]"`},
+		{`json_stringify({"f": [1, fn(x) { x }]})`, `ERROR: json_stringify: FUNCTION at $["f"][1] cannot be represented in JSON`},
+		{`json_stringify({1: 2})`, "ERROR: json_stringify: key 1 of the object at $ is INTEGER, JSON keys must be STRING"},
+		{`json_stringify(1, -1)`, "ERROR: indent given to 'json_stringify' must be a non-negative INTEGER, got -1"},
+	}
+	for _, test := range tests {
+		if got := testEval(test.input).Inspect(); got != test.want {
+			t.Errorf("%s: result not %s. got=%s", test.input, test.want, got)
+		}
+	}
+}
+
+func TestRegex(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{`/a\/b+/`, `/a\/b+/`},
+		{`regex("(\\d+)-(\\d+)")`, `/(\d+)-(\d+)/`},
+		{`regex("a(")`, "ERROR: regex: error parsing regexp: missing closing ): `a(`"},
+		{`match(/(\d+)-(\d+)/, "from 10-20")`, `["10-20", "10", "20"]`},
+		{`match(/(a)|(b)/, "b")`, `["b", null, "b"]`},
+		{`match(/x/, "abc")`, "null"},
+		{`find_all(/\d+/, "1 22 333")`, `["1", "22", "333"]`},
+		{`find_all(/x/, "abc")`, "[]"},
+		{`replace_all(/(\w+)@(\w+)/, "me@home you@work", "$2:$1")`, `"home:me work:you"`},
+		{`replace_all(/<(\d+)>/, "a<1>b<22>", fn(m) { m[1] })`, `"a1b22"`},
+		{`replace_all(/\d/, "a1", fn(m) { len(m) })`, "ERROR: replacement function given to 'replace_all' must return STRING, got INTEGER"},
+		{`replace_all(/\d/, "a1", fn(m) { m[0] + 1 })`, "ERROR: type mismatch: STRING + INTEGER"},
+		{`replace_all(/\d/, "a1", 1)`, "ERROR: replacement given to 'replace_all' must be STRING or FUNCTION, got INTEGER"},
+		{`split(/\s*,\s*/, "a , b,c")`, `["a", "b", "c"]`},
+		{`match("x", "x")`, "ERROR: first argument to 'match' must be REGEX, got STRING"},
+		{`split(/,/, 1)`, "ERROR: argument to 'split' must be STRING, got INTEGER"},
+		{`find_all(/x/)`, "ERROR: wrong number of arguments. got=1, want=2"},
+	}
+	for _, test := range tests {
+		if got := testEval(test.input).Inspect(); got != test.want {
+			t.Errorf("%s: result not %s. got=%s", test.input, test.want, got)
+		}
+	}
+}
+
+func TestIOBuiltins(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "f.txt")
+	env := object.NewEnvWithRuntime(&object.Runtime{
+		Capabilities: object.CAP_ALL,
+		Stdin:        strings.NewReader("one\r\ntwo\nrest"),
+	})
+	env.Set("dir", &object.String{Value: dir})
+	env.Set("path", &object.String{Value: path})
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{`write_file(path, "a")`, "null"},
+		{`append_file(path, "b\n")`, "null"},
+		{`read_file(path)`, `"ab\n"`},
+		{`exists(path)`, "true"},
+		{`list_dir(dir)`, `["f.txt"]`},
+		{`remove(path)`, "null"},
+		{`exists(path)`, "false"},
+		{`read_file(path)`, "ERROR: read_file: open " + path + ": no such file or directory"},
+		{`remove(path)`, "ERROR: remove: remove " + path + ": no such file or directory"},
+		{`list_dir(path)`, "ERROR: list_dir: open " + path + ": no such file or directory"},
+		{`read_file(1)`, "ERROR: argument to 'read_file' must be STRING, got INTEGER"},
+		{`write_file(path)`, "ERROR: wrong number of arguments. got=1, want=2"},
+		{`read_line()`, `"one"`},
+		{`read_line()`, `"two"`},
+		{`read_all()`, `"rest"`},
+		{`read_line()`, "null"},
+	}
+	for _, test := range tests {
+		program := parser.New(lexer.New(test.input)).Parse()
+		if got := Eval(&program, env).Inspect(); got != test.want {
+			t.Errorf("%s: result not %s. got=%s", test.input, test.want, got)
+		}
+	}
+}
+
+func TestProcessBuiltins(t *testing.T) {
+	t.Setenv("MONKEY_TEST_HOME", "/home/monkey")
+	os.Unsetenv("MONKEY_TEST_UNSET")
+	defer os.Unsetenv("MONKEY_TEST_SET")
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{`getenv("MONKEY_TEST_HOME")`, `"/home/monkey"`},
+		{`getenv("MONKEY_TEST_UNSET")`, "null"},
+		{`setenv("MONKEY_TEST_SET", "1"); getenv("MONKEY_TEST_SET")`, `"1"`},
+		{`getenv(1)`, "ERROR: argument to 'getenv' must be STRING, got INTEGER"},
+		{`exit("1")`, "ERROR: argument to 'exit' must be INTEGER, got STRING"},
+	}
+	for _, test := range tests {
+		program := parser.New(lexer.New(test.input)).Parse()
+		env := object.NewEnvWithRuntime(&object.Runtime{Capabilities: object.CAP_ENV})
+		if got := Eval(&program, env).Inspect(); got != test.want {
+			t.Errorf("%s: result not %s. got=%s", test.input, test.want, got)
+		}
+	}
+}
+
+func TestBigIntegers(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"9223372036854775807 + 1", "9223372036854775808"},
+		{"9223372036854775808", "9223372036854775808"},
+		{"-9223372036854775808 == -9223372036854775807 - 1", "true"},
+		{"18446744073709551616 == pow(2, 64)", "true"},
+		{"-9223372036854775807 - 2", "-9223372036854775809"},
+		{"-(-9223372036854775807 - 1)", "9223372036854775808"},
+		{"4611686018427387904 * 2", "9223372036854775808"},
+		{"4611686018427387904 * -2", "-9223372036854775808"},
+		{"(-9223372036854775807 - 1) / -1", "9223372036854775808"},
+		{"9223372036854775807 + 1 - 1", "9223372036854775807"},
+		{"pow(10, 20) / pow(10, 19) == 10", "true"},
+		{"pow(10, 20) % 7", "2"},
+		{"pow(2, 64) > 1", "true"},
+		{"-pow(2, 64) < pow(2, 63)", "true"},
+		{"pow(2, 64) == pow(2, 64)", "true"},
+		{"pow(2, 64) + true", "ERROR: type mismatch: BIG_INTEGER + BOOLEAN"},
+		{`{pow(2, 64): "big"}[pow(4, 32)]`, `"big"`},
+		{"json_stringify([pow(2, 64)])", `"[18446744073709551616]"`},
+	}
+	for _, test := range tests {
+		if got := testEval(test.input).Inspect(); got != test.want {
+			t.Errorf("%s: result not %s. got=%s", test.input, test.want, got)
+		}
+	}
+}
+
+func TestMath(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"abs(-3)", "3"},
+		{"abs(-9223372036854775807 - 1)", "9223372036854775808"},
+		{"min(3, -1, 2)", "-1"},
+		{"max(3, pow(2, 70), 2)", "1180591620717411303424"},
+		{"max()", "ERROR: wrong number of arguments. got=0, want=1 or more"},
+		{`min(1, "2")`, "ERROR: argument to 'min' must be INTEGER, got STRING"},
+		{"pow(3, 4)", "81"},
+		{"pow(2, 0)", "1"},
+		{"pow(2, -1)", "ERROR: exponent given to 'pow' must not be negative, got -1"},
+		{"sqrt(17)", "4"},
+		{"sqrt(pow(10, 40))", "100000000000000000000"},
+		{"sqrt(-4)", "ERROR: argument to 'sqrt' must not be negative, got -4"},
+		{"gcd(12, -18)", "6"},
+		{"gcd(0, 0)", "0"},
+		{"gcd(1)", "ERROR: wrong number of arguments. got=1, want=2"},
+	}
+	for _, test := range tests {
+		if got := testEval(test.input).Inspect(); got != test.want {
+			t.Errorf("%s: result not %s. got=%s", test.input, test.want, got)
+		}
+	}
+}
+
+func TestRandom(t *testing.T) {
+	draw := func(input string) string {
+		program := parser.New(lexer.New(input)).Parse()
+		env := object.NewEnvWithRuntime(&object.Runtime{Capabilities: object.CAP_RANDOM})
+		return Eval(&program, env).Inspect()
+	}
+	input := "seed(42); [random(1000), random(1000), await(spawn(fn() { random(1000) }))]"
+	if first, second := draw(input), draw(input); first != second {
+		t.Errorf("seeded draws differ. first=%s, second=%s", first, second)
+	}
+	for i := 0; i < 20; i++ {
+		if got := draw("let n = random(3); if (n < 3) { n > -1 } else { false }"); got != "true" {
+			t.Fatalf("random(3) out of range. got=%s", got)
+		}
+	}
+	if got, want := draw("random(0)"), "ERROR: argument to 'random' must be a positive INTEGER, got 0"; got != want {
+		t.Errorf("result not %s. got=%s", want, got)
+	}
+}
+
+func TestCollections(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"map([1, 2, 3], fn(x) { x * x })", "[1, 4, 9]"},
+		{"map([], fn(x) { x })", "[]"},
+		{"map([1], fn(x) {})", "[null]"},
+		{"map([[1, 2], [3]], len)", "[2, 1]"},
+		{"filter(range(10), fn(x) { x % 3 == 0 })", "[0, 3, 6, 9]"},
+		{"reduce([1, 2, 3, 4], fn(acc, x) { acc * 10 + x }, 0)", "1234"},
+		{"reduce([], fn(acc, x) { acc + x }, 7)", "7"},
+		{`sort_by(["ccc", "a", "bb"], len)`, `["a", "bb", "ccc"]`},
+		{`sort_by([{"n": "b", "k": 1}, {"n": "a", "k": 0}, {"n": "c", "k": 1}], fn(h) { h["k"] })`,
+			`[{"n": "a", "k": 0}, {"n": "b", "k": 1}, {"n": "c", "k": 1}]`},
+		{`sort_by(["b", "a"], fn(s) { s })`, `["a", "b"]`},
+		{"sort_by([3, 1, 2], fn(x) { -x })", "[3, 2, 1]"},
+		{`sort_by([1, "a"], fn(x) { x })`, `ERROR: sort_by: key "a" of element 1 cannot be compared with key 1`},
+		{"sort_by([1, 2], fn(x) { true })", "ERROR: sort_by: key true of element 0 is BOOLEAN, keys must be INTEGER or STRING"},
+		{"sort_by([1, 2], fn(x) {})", "ERROR: sort_by: key null of element 0 is NULL, keys must be INTEGER or STRING"},
+		{"sort_by([1, 2], fn(x) { if (x > 1) { true } else { 1 } })", "ERROR: sort_by: key true of element 1 cannot be compared with key 1"},
+		{"any([1, 2, 3], fn(x) { x > 2 })", "true"},
+		{"any([], fn(x) { true })", "false"},
+		{"all([1, 2, 3], fn(x) { x > 0 })", "true"},
+		{"all([1, 2, 3], fn(x) { x > 1 })", "false"},
+		{"all([0, 1], fn(x) { if (x > 0) { 1 / 0 } else { false } })", "false"},
+		{"any([0, 1], fn(x) { 1 / x })", "ERROR: division by zero: 1 / 0"},
+		{`zip([1, 2, 3], ["a", "b"])`, `[[1, "a"], [2, "b"]]`},
+		{"zip([1], 2)", "ERROR: argument to 'zip' must be ARRAY, got INTEGER"},
+		{"range(3)", "[0, 1, 2]"},
+		{"range(2, 5)", "[2, 3, 4]"},
+		{"range(5, 0, -2)", "[5, 3, 1]"},
+		{"range(3, 1)", "[]"},
+		{"range(9223372036854775806, 9223372036854775807, 5)", "[9223372036854775806]"},
+		{"range(0, 1, 0)", "ERROR: step given to 'range' must not be 0"},
+		{"map([1, 0], fn(x) { 10 / x })", "ERROR: division by zero: 10 / 0"},
+		{"map([1], fn(x, y) { x })", "ERROR: wrong number of arguments: want=2, got=1"},
+		{"map([1], 1)", "ERROR: second argument to 'map' must be FUNCTION, got INTEGER"},
+		{`filter("abc", fn(x) { x })`, "ERROR: first argument to 'filter' must be ARRAY, got STRING"},
+		{"reduce([1], fn(a, x) { a })", "ERROR: wrong number of arguments. got=2, want=3"},
+	}
+	for _, test := range tests {
+		if got := testEval(test.input).Inspect(); got != test.want {
+			t.Errorf("%s: result not %s. got=%s", test.input, test.want, got)
+		}
+	}
+}
+
+func TestTime(t *testing.T) {
+	frozen := time.Date(2024, 2, 28, 23, 30, 0, 0, time.UTC)
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"now()", "2024-02-28T23:30:00Z"},
+		{"await(spawn(fn() { now() }))", "2024-02-28T23:30:00Z"},
+		{`add_duration(now(), "1h")`, "2024-02-29T00:30:00Z"},
+		{"add_duration(now(), -90)", "2024-02-28T23:28:30Z"},
+		{`add_duration(now(), "1 day")`, `ERROR: add_duration: time: unknown unit " day" in duration "1 day"`},
+		{`add_duration(now(), true)`, "ERROR: duration given to 'add_duration' must be STRING or INTEGER, got BOOLEAN"},
+		{`parse_time("2024-03-01 12:00", "2006-01-02 15:04")`, "2024-03-01T12:00:00Z"},
+		{`parse_time("2024-03-01T12:00:00+09:00", "RFC3339")`, "2024-03-01T12:00:00+09:00"},
+		{`parse_time("March", "DateOnly")`, `ERROR: parse_time: parsing time "March" as "2006-01-02": cannot parse "March" as "2006"`},
+		{`format_time(now(), "Mon Jan 2 15:04")`, `"Wed Feb 28 23:30"`},
+		{`format_time(now(), "Kitchen")`, `"11:30PM"`},
+		{`diff(parse_time("2024-03-01", "DateOnly"), now())`, "88200"},
+		{`diff(now(), add_duration(now(), "1500ms"))`, "-1"},
+		{"unix(now())", "1709163000"},
+		{"from_unix(0)", "1970-01-01T00:00:00Z"},
+		{`in_zone(now(), "Asia/Tokyo")`, "2024-02-29T08:30:00+09:00"},
+		{`zone(in_zone(now(), "Asia/Tokyo"))`, `"Asia/Tokyo"`},
+		{`unix(in_zone(now(), "Asia/Tokyo")) == unix(now())`, "true"},
+		{`in_zone(now(), "Mars/Olympus")`, "ERROR: in_zone: unknown time zone Mars/Olympus"},
+		{`unix("now")`, "ERROR: argument to 'unix' must be TIME, got STRING"},
+	}
+	for _, test := range tests {
+		program := parser.New(lexer.New(test.input)).Parse()
+		env := object.NewEnvWithRuntime(&object.Runtime{
+			Capabilities: object.CAP_CLOCK,
+			Clock:        func() time.Time { return frozen },
+		})
+		if got := Eval(&program, env).Inspect(); got != test.want {
+			t.Errorf("%s: result not %s. got=%s", test.input, test.want, got)
+		}
+	}
+}
+
+func TestExit(t *testing.T) {
+	tests := []struct {
+		input string
+		code  int
+	}{
+		{"exit()", 0},
+		{"exit(3); 1", 3},
+		{"let f = fn() { if (true) { exit(2); } 5 }; f(); 9", 2},
+		{"assert_error(fn() { exit(4) }); 1", 4},
+		{"await(spawn(fn() { exit(5) })); 1", 5},
+	}
+	for _, test := range tests {
+		evaluated := testEval(test.input)
+		errObj, ok := evaluated.(*object.Error)
+		if !ok || !errObj.Exit {
+			t.Errorf("%s: no exit returned. got=%T(%+v)", test.input, evaluated, evaluated)
+			continue
+		}
+		if errObj.Code != test.code {
+			t.Errorf("%s: exit code not %d. got=%d", test.input, test.code, errObj.Code)
+		}
+	}
+}
+
 func TestEvalBoolExpression(t *testing.T) {
 	tests := []struct {
 		input string
@@ -188,6 +613,13 @@ func TestErrorHandling(t *testing.T) {
 			9;
 		}
 		`, "type mismatch: INTEGER + BOOLEAN"},
+		{"let f = fn(x, y) { x }; f(1);", "wrong number of arguments: want=2, got=1"},
+		{"fn(x) { x }(1, 2);", "wrong number of arguments: want=1, got=2"},
+		{"let x = 0; 1 / x", "division by zero: 1 / 0"},
+		{"len(5 + true)", "type mismatch: INTEGER + BOOLEAN"},
+		{"let f = fn(x) { x }; f(f(1 / 0))", "division by zero: 1 / 0"},
+		{"-7 % 0", "division by zero: -7 % 0"},
+		{"pow(2, 64) / 0", "division by zero: 18446744073709551616 / 0"},
 	}
 	for _, test := range tests {
 		evaluated := testEval(test.input)
@@ -202,6 +634,43 @@ func TestErrorHandling(t *testing.T) {
 	}
 }
 
+func TestAssertions(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string // error message, or "" when the assertion holds
+	}{
+		{"assert(1 < 2)", ""},
+		{"assert(1 > 2)", "assertion failed"},
+		{`assert(false, "too small")`, "assertion failed: too small"},
+		{"assert_eq(1 + 2, 3)", ""},
+		{"assert_eq(1 + 2, 4)", "assert_eq failed:\n--- want\n+++ got\n@@ -1,1 +1,1 @@\n-4\n+3"},
+		{`assert_eq("1", 1)`, "assert_eq failed:\n--- want\n+++ got\n@@ -1,1 +1,1 @@\n-1\n+\"1\""},
+		{"assert_eq(fn() {}(), if (false) { 1 })", ""},
+		{"assert_error(fn() { 1 + true })", ""},
+		{`assert_error(fn() { 1 + true }, "type mismatch")`, ""},
+		{`assert_error(fn() { 1 + true }, "not found")`, `assert_error failed: error "type mismatch: INTEGER + BOOLEAN" does not contain "not found"`},
+		{"assert_error(fn() { 1 })", "assert_error failed: no error, got 1"},
+		{"assert_error(1)", "argument to 'assert_error' must be FUNCTION, got INTEGER"},
+	}
+	for _, test := range tests {
+		evaluated := testEval(test.input)
+		errObj, ok := evaluated.(*object.Error)
+		if test.want == "" {
+			if evaluated != NULL {
+				t.Errorf("%s: result not null. got=%T(%+v)", test.input, evaluated, evaluated)
+			}
+			continue
+		}
+		if !ok {
+			t.Errorf("%s: no error object returned. got=%T(%+v)", test.input, evaluated, evaluated)
+			continue
+		}
+		if errObj.Message != test.want {
+			t.Errorf("%s: wrong error message. expected=%q, got=%q", test.input, test.want, errObj.Message)
+		}
+	}
+}
+
 func TestEvalPlus(t *testing.T) {
 	input := `
 	let five = 5;
@@ -228,6 +697,9 @@ func TestFunctionObject(t *testing.T) {
 	if len(fn.Parameters) != 1 {
 		t.Errorf("function has wrong parameters. Parameters=%+v", fn.Parameters)
 	}
+	if got, want := fn.Inspect(), "fn(x) { ... }"; got != want {
+		t.Errorf("fn.Inspect() not %q. got=%q", want, got)
+	}
 	// TODO
 }
 
@@ -255,6 +727,359 @@ func TestFunctionApplication(t *testing.T) {
 	}
 }
 
+func TestConcurrentEvaluationSharedPrelude(t *testing.T) {
+	prelude := object.NewEnv()
+	program := parser.New(lexer.New(`
+	let STEP = 10;
+	let add = fn(a, b) { a + b };
+	let fib = fn(x) { if (x < 2) { return x; } fib(x - 1) + fib(x - 2) };
+	`)).Parse()
+	Eval(&program, prelude)
+	prelude.Freeze()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 32; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			var out bytes.Buffer
+			rt := &object.Runtime{Capabilities: object.CAP_STDOUT, Stdout: &out}
+			env := object.NewEnclosedEnvironment(prelude)
+			env.SetRuntime(rt)
+			input := fmt.Sprintf(`
+			let STEP = %d;
+			let n = add(STEP, fib(10));
+			puts(n);
+			n;
+			`, i)
+			program := parser.New(lexer.New(input)).Parse()
+			evaluated := Eval(&program, env)
+			testIntegerObject(t, evaluated, int64(i+55))
+			if got, want := out.String(), fmt.Sprintf("%d\n", i+55); got != want {
+				t.Errorf("output not %q. got=%q", want, got)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	step, _ := prelude.Get("STEP")
+	testIntegerObject(t, step, 10)
+}
+
+func TestSharedEnvironmentConcurrentAccess(t *testing.T) {
+	shared := object.NewEnv()
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			env := object.NewEnclosedEnvironment(shared)
+			program := parser.New(lexer.New(`let counter = 1; counter + 1`)).Parse()
+			Eval(&program, env)
+			shared.Set(fmt.Sprintf("v%d", i), &object.Integer{Value: int64(i)})
+			shared.Get("v0")
+		}(i)
+	}
+	wg.Wait()
+	for i := 0; i < 16; i++ {
+		v, ok := shared.Get(fmt.Sprintf("v%d", i))
+		if !ok {
+			t.Errorf("v%d not set", i)
+			continue
+		}
+		testIntegerObject(t, v, int64(i))
+	}
+}
+
+func TestLetOnFrozenEnvironment(t *testing.T) {
+	env := object.NewEnv()
+	env.Freeze()
+	program := parser.New(lexer.New("let x = 1;")).Parse()
+	evaluated := Eval(&program, env)
+	errObj, ok := evaluated.(*object.Error)
+	if !ok {
+		t.Fatalf("no error object returned. got=%T(%+v)", evaluated, evaluated)
+	}
+	if want := "cannot bind x: environment is frozen"; errObj.Message != want {
+		t.Errorf("wrong error message. expected=%q, got=%q", want, errObj.Message)
+	}
+}
+
+func TestFreezeWhileShared(t *testing.T) {
+	env := object.NewEnv()
+	env.Set("x", &object.Integer{Value: 1})
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			program := parser.New(lexer.New("let y = x; x")).Parse()
+			Eval(&program, object.NewEnclosedEnvironment(env))
+			env.Set("z", &object.Integer{Value: 2})
+		}()
+	}
+	env.Freeze()
+	wg.Wait()
+	if err := env.Set("x", &object.Integer{Value: 3}); err != object.ErrFrozen {
+		t.Errorf("Set on frozen environment not ErrFrozen. got=%v", err)
+	}
+	x, _ := env.Get("x")
+	testIntegerObject(t, x, 1)
+}
+
+func TestSpawnAndAwait(t *testing.T) {
+	tests := []struct {
+		input string
+		want  int64
+	}{
+		{"let t = spawn(fn() { 1 + 2 }); await(t);", 3},
+		{"let add = fn(a, b) { a + b }; await(spawn(add, 40, 2));", 42},
+		{`
+		let fib = fn(x) { if (x < 2) { return x; } fib(x - 1) + fib(x - 2) };
+		let a = spawn(fn() { fib(15) });
+		let b = spawn(fn() { fib(16) });
+		await(a) + await(b);
+		`, 1597},
+		{`
+		let base = 100;
+		let t = spawn(fn() { let base = 1; base });
+		await(t) + base;
+		`, 101},
+	}
+	for _, test := range tests {
+		evaluated := testEval(test.input)
+		testIntegerObject(t, evaluated, test.want)
+	}
+}
+
+func TestPanicInTask(t *testing.T) {
+	env := object.NewEnv()
+	env.Set("boom", &object.Builtin{Fn: func(env *object.Environment, args ...object.Object) object.Object {
+		panic("boom")
+	}})
+	program := parser.New(lexer.New("await(spawn(boom));")).Parse()
+	evaluated := Eval(&program, env)
+	errObj, ok := evaluated.(*object.Error)
+	if !ok {
+		t.Fatalf("no error object returned. got=%T(%+v)", evaluated, evaluated)
+	}
+	if want := "task panicked: boom"; errObj.Message != want {
+		t.Errorf("wrong error message. expected=%q, got=%q", want, errObj.Message)
+	}
+}
+
+func TestChannels(t *testing.T) {
+	tests := []struct {
+		input string
+		want  interface{}
+	}{
+		{"let c = channel(1); send(c, 5); recv(c);", 5},
+		{`
+		let c = channel();
+		let producer = fn(n) { if (n > 0) { send(c, n); producer(n - 1); } else { close(c); } };
+		spawn(producer, 4);
+		recv(c) + recv(c) + recv(c) + recv(c);
+		`, 10},
+		{"let c = channel(1); close(c); recv(c);", nil},
+		{`
+		let a = channel();
+		let b = channel();
+		spawn(fn() { send(b, 7) });
+		select(a, b);
+		`, 7},
+		{`
+		let a = channel();
+		let b = channel();
+		close(a);
+		close(b);
+		select(a, b);
+		`, nil},
+		{"let c = channel(); close(c); send(c, 1);", "send on closed channel"},
+		{"let c = channel(); close(c); close(c);", "close of closed channel"},
+		{"await(1);", "argument to 'await' must be TASK, got INTEGER"},
+		{"spawn(1);", "argument to 'spawn' must be FUNCTION, got INTEGER"},
+	}
+	for _, test := range tests {
+		evaluated := testEval(test.input)
+		switch want := test.want.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(want))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("no error object returned. got=%T(%+v)", evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != want {
+				t.Errorf("wrong error message. expected=%q, got=%q", want, errObj.Message)
+			}
+		default:
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
+func TestImport(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"lib/strings.monkey": `
+		let helpers = import("helpers.monkey");
+		let shout = fn(s) { helpers.twice(s) };
+		puts("loading strings");
+		`,
+		"lib/helpers.monkey": `let twice = fn(s) { s + s };`,
+		"cycle/a.monkey":     `let b = import("b.monkey");`,
+		"cycle/b.monkey":     `let a = import("a.monkey");`,
+	}
+	for name, src := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		input string
+		want  interface{}
+		out   string
+	}{
+		{`let m = import("lib/strings.monkey"); m.shout(21);`, 42, "loading strings\n"},
+		{`
+		let a = import("lib/strings.monkey");
+		let b = import("lib/strings.monkey");
+		a.shout(1) + b.shout(2);
+		`, 6, "loading strings\n"},
+		{`import("lib/strings.monkey").helpers.twice(5)`, 10, "loading strings\n"},
+		{`let m = import("lib/helpers.monkey"); m.nope;`, "has no binding nope", ""},
+		{`let m = import("missing.monkey");`, "no such file or directory", ""},
+		{`let m = import("cycle/a.monkey");`, "import cycle: " +
+			filepath.Join(dir, "cycle/a.monkey") + " -> " +
+			filepath.Join(dir, "cycle/b.monkey") + " -> " +
+			filepath.Join(dir, "cycle/a.monkey"), ""},
+		{`let x = 1; x.y`, "member access not supported: INTEGER", ""},
+	}
+	for _, test := range tests {
+		var out bytes.Buffer
+		rt := &object.Runtime{Capabilities: object.CAP_ALL, Stdout: &out}
+		env := object.NewEnvWithRuntime(rt)
+		env.SetFile(&object.File{Path: filepath.Join(dir, "main.monkey")})
+		program := parser.New(lexer.New(test.input)).Parse()
+		evaluated := Eval(&program, env)
+		switch want := test.want.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(want))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("no error object returned. got=%T(%+v)", evaluated, evaluated)
+				continue
+			}
+			if !strings.Contains(errObj.Message, want) {
+				t.Errorf("error message %q does not contain %q", errObj.Message, want)
+			}
+		}
+		if got := out.String(); got != test.out {
+			t.Errorf("output not %q. got=%q", test.out, got)
+		}
+	}
+}
+
+func TestConcurrentImportCycle(t *testing.T) {
+	// Two tasks each start loading one module, and then import the one
+	// the other is loading.
+	var cache object.ModuleCache
+	a, b := &object.Runtime{}, &object.Runtime{}
+	aStarted, bStarted := make(chan struct{}), make(chan struct{})
+	results := make(chan object.Object, 2)
+	go func() {
+		results <- cache.Load(a, "a.monkey", func() object.Object {
+			close(aStarted)
+			<-bStarted
+			return cache.Load(a, "b.monkey", nil)
+		})
+	}()
+	go func() {
+		results <- cache.Load(b, "b.monkey", func() object.Object {
+			close(bStarted)
+			<-aStarted
+			return cache.Load(b, "a.monkey", nil)
+		})
+	}()
+	for i := 0; i < 2; i++ {
+		select {
+		case result := <-results:
+			errObj, ok := result.(*object.Error)
+			if !ok {
+				t.Fatalf("no error object returned. got=%T(%+v)", result, result)
+			}
+			if !strings.HasPrefix(errObj.Message, "import cycle: ") {
+				t.Errorf("wrong error message. got=%q", errObj.Message)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("import deadlocked")
+		}
+	}
+}
+
+func TestImportRequiresFSRead(t *testing.T) {
+	evaluated := testEval(`import("lib.monkey")`)
+	errObj, ok := evaluated.(*object.Error)
+	if !ok {
+		t.Fatalf("no error object returned. got=%T(%+v)", evaluated, evaluated)
+	}
+	if want := "permission denied: fs-read capability not granted"; errObj.Message != want {
+		t.Errorf("wrong error message. expected=%q, got=%q", want, errObj.Message)
+	}
+}
+
+// recorder is a Tracer logging the events it sees.
+type recorder struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (r *recorder) log(format string, a ...interface{}) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, fmt.Sprintf(format, a...))
+}
+
+func (r *recorder) Statement(stmt ast.Statement, env *object.Environment) {
+	r.log("stmt %d", ast.Pos(stmt).Line)
+}
+func (r *recorder) Call(fn *object.Function, env *object.Environment) { r.log("call %s", fn.Name) }
+func (r *recorder) Return(fn *object.Function, result object.Object) {
+	r.log("return %s %s", fn.Name, result.Inspect())
+}
+func (r *recorder) Branch(ie *ast.IfExpression, consequence bool, env *object.Environment) {
+	r.log("branch %d %t", ast.Pos(ie).Line, consequence)
+}
+func (r *recorder) Spawn() object.Tracer { return r }
+
+func TestTracer(t *testing.T) {
+	input := `let double = fn(x) {
+	if (x > 1) { x * 2 } else { 2 }
+};
+await(spawn(double, 1));
+double(2);`
+	r := &recorder{}
+	env := object.NewEnvWithRuntime(&object.Runtime{Tracer: r})
+	program := parser.New(lexer.New(input)).Parse()
+	Eval(&program, env)
+	want := []string{
+		"stmt 1", "stmt 4",
+		"call double", "stmt 2", "branch 2 false", "stmt 2", "return double 2",
+		"stmt 5",
+		"call double", "stmt 2", "branch 2 true", "stmt 2", "return double 4",
+	}
+	if strings.Join(r.events, ", ") != strings.Join(want, ", ") {
+		t.Errorf("events not %q. got=%q", want, r.events)
+	}
+}
+
 func testEval(input string) object.Object {
 	l := lexer.New(input)
 	p := parser.New(l)
@@ -0,0 +1,160 @@
+package evaluator
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"strings"
+
+	"github.com/shozawa/monkey/object"
+)
+
+// Failing I/O returns an error carrying the message of the OS error,
+// such as "read_file: open x: no such file or directory".
+
+func init() {
+	builtins["read_line"] = &object.Builtin{Capabilities: object.CAP_STDIN, Fn: readLine}
+	builtins["read_all"] = &object.Builtin{Capabilities: object.CAP_STDIN, Fn: readAll}
+	builtins["read_file"] = &object.Builtin{Capabilities: object.CAP_FS_READ, Fn: readFile}
+	builtins["write_file"] = &object.Builtin{Capabilities: object.CAP_FS_WRITE, Fn: writeFile}
+	builtins["append_file"] = &object.Builtin{Capabilities: object.CAP_FS_WRITE, Fn: appendFile}
+	builtins["list_dir"] = &object.Builtin{Capabilities: object.CAP_FS_READ, Fn: listDir}
+	builtins["exists"] = &object.Builtin{Capabilities: object.CAP_FS_READ, Fn: exists}
+	builtins["remove"] = &object.Builtin{Capabilities: object.CAP_FS_WRITE, Fn: remove}
+}
+
+// stringArgs checks that args are n strings and returns their values.
+func stringArgs(name string, args []object.Object, n int) ([]string, *object.Error) {
+	if len(args) != n {
+		return nil, newError("wrong number of arguments. got=%d, want=%d", len(args), n)
+	}
+	values := make([]string, n)
+	for i, arg := range args {
+		str, ok := arg.(*object.String)
+		if !ok {
+			return nil, newError("argument to '%s' must be STRING, got %s", name, arg.Type())
+		}
+		values[i] = str.Value
+	}
+	return values, nil
+}
+
+func osError(name string, err error) *object.Error {
+	return newError("%s: %s", name, err)
+}
+
+// readLine returns the next line of input without its line ending, or
+// null at the end of the input.
+func readLine(env *object.Environment, args ...object.Object) object.Object {
+	if _, errObj := stringArgs("read_line", args, 0); errObj != nil {
+		return errObj
+	}
+	line, err := env.Runtime().In().ReadString('\n')
+	if err == io.EOF && line == "" {
+		return NULL
+	}
+	if err != nil && err != io.EOF {
+		return osError("read_line", err)
+	}
+	line = strings.TrimSuffix(line, "\n")
+	line = strings.TrimSuffix(line, "\r")
+	return &object.String{Value: line}
+}
+
+func readAll(env *object.Environment, args ...object.Object) object.Object {
+	if _, errObj := stringArgs("read_all", args, 0); errObj != nil {
+		return errObj
+	}
+	data, err := io.ReadAll(env.Runtime().In())
+	if err != nil {
+		return osError("read_all", err)
+	}
+	return &object.String{Value: string(data)}
+}
+
+func readFile(env *object.Environment, args ...object.Object) object.Object {
+	values, errObj := stringArgs("read_file", args, 1)
+	if errObj != nil {
+		return errObj
+	}
+	data, err := os.ReadFile(values[0])
+	if err != nil {
+		return osError("read_file", err)
+	}
+	return &object.String{Value: string(data)}
+}
+
+func writeFile(env *object.Environment, args ...object.Object) object.Object {
+	values, errObj := stringArgs("write_file", args, 2)
+	if errObj != nil {
+		return errObj
+	}
+	if err := os.WriteFile(values[0], []byte(values[1]), 0644); err != nil {
+		return osError("write_file", err)
+	}
+	return NULL
+}
+
+func appendFile(env *object.Environment, args ...object.Object) object.Object {
+	values, errObj := stringArgs("append_file", args, 2)
+	if errObj != nil {
+		return errObj
+	}
+	f, err := os.OpenFile(values[0], os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return osError("append_file", err)
+	}
+	_, err = f.WriteString(values[1])
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return osError("append_file", err)
+	}
+	return NULL
+}
+
+// listDir returns the names of the entries of a directory, sorted.
+func listDir(env *object.Environment, args ...object.Object) object.Object {
+	values, errObj := stringArgs("list_dir", args, 1)
+	if errObj != nil {
+		return errObj
+	}
+	entries, err := os.ReadDir(values[0])
+	if err != nil {
+		return osError("list_dir", err)
+	}
+	names := make([]object.Object, len(entries))
+	for i, entry := range entries {
+		names[i] = &object.String{Value: entry.Name()}
+	}
+	return &object.Array{Elements: names}
+}
+
+func exists(env *object.Environment, args ...object.Object) object.Object {
+	values, errObj := stringArgs("exists", args, 1)
+	if errObj != nil {
+		return errObj
+	}
+	_, err := os.Stat(values[0])
+	if errors.Is(err, fs.ErrNotExist) {
+		return FALSE
+	}
+	if err != nil {
+		return osError("exists", err)
+	}
+	return TRUE
+}
+
+// remove deletes a file or an empty directory.
+func remove(env *object.Environment, args ...object.Object) object.Object {
+	values, errObj := stringArgs("remove", args, 1)
+	if errObj != nil {
+		return errObj
+	}
+	if err := os.Remove(values[0]); err != nil {
+		return osError("remove", err)
+	}
+	return NULL
+}
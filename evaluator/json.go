@@ -0,0 +1,298 @@
+package evaluator
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/shozawa/monkey/object"
+)
+
+// JSON objects become hashes with string keys, arrays arrays, numbers
+// integers, big ones included, and null NULL. Monkey has no floats, so
+// a number with a fraction or exponent is an error.
+
+func init() {
+	builtins["json_parse"] = &object.Builtin{Fn: jsonParse}
+	builtins["json_stringify"] = &object.Builtin{Fn: jsonStringify}
+}
+
+func jsonParse(env *object.Environment, args ...object.Object) object.Object {
+	values, errObj := stringArgs("json_parse", args, 1)
+	if errObj != nil {
+		return errObj
+	}
+	p := &jsonParser{src: values[0]}
+	value := p.value()
+	if isError(value) {
+		return value
+	}
+	p.skipSpace()
+	if p.pos < len(p.src) {
+		return p.errorf("unexpected %q after value", p.src[p.pos])
+	}
+	return value
+}
+
+// jsonParser decodes JSON, keeping the byte offset it has reached for
+// error messages.
+type jsonParser struct {
+	src string
+	pos int
+}
+
+func (p *jsonParser) errorf(format string, a ...interface{}) *object.Error {
+	return newError("json_parse: %s at offset %d", fmt.Sprintf(format, a...), p.pos)
+}
+
+func (p *jsonParser) skipSpace() {
+	for p.pos < len(p.src) && strings.IndexByte(" \t\r\n", p.src[p.pos]) >= 0 {
+		p.pos++
+	}
+}
+
+// expect consumes c, the next character after any white space.
+func (p *jsonParser) expect(c byte) *object.Error {
+	p.skipSpace()
+	if p.pos >= len(p.src) {
+		return p.errorf("unexpected end of input, want %q", c)
+	}
+	if p.src[p.pos] != c {
+		return p.errorf("unexpected %q, want %q", p.src[p.pos], c)
+	}
+	p.pos++
+	return nil
+}
+
+func (p *jsonParser) value() object.Object {
+	p.skipSpace()
+	if p.pos >= len(p.src) {
+		return p.errorf("unexpected end of input")
+	}
+	switch c := p.src[p.pos]; {
+	case c == '{':
+		return p.object()
+	case c == '[':
+		return p.array()
+	case c == '"':
+		return p.string()
+	case c == '-' || '0' <= c && c <= '9':
+		return p.number()
+	case strings.HasPrefix(p.src[p.pos:], "true"):
+		p.pos += len("true")
+		return TRUE
+	case strings.HasPrefix(p.src[p.pos:], "false"):
+		p.pos += len("false")
+		return FALSE
+	case strings.HasPrefix(p.src[p.pos:], "null"):
+		p.pos += len("null")
+		return NULL
+	default:
+		return p.errorf("unexpected %q", c)
+	}
+}
+
+func (p *jsonParser) object() object.Object {
+	p.pos++ // consume '{'
+	hash := object.NewHash()
+	p.skipSpace()
+	if p.pos < len(p.src) && p.src[p.pos] == '}' {
+		p.pos++
+		return hash
+	}
+	for {
+		p.skipSpace()
+		if p.pos >= len(p.src) || p.src[p.pos] != '"' {
+			if p.pos >= len(p.src) {
+				return p.errorf("unexpected end of input, want object key")
+			}
+			return p.errorf("unexpected %q, want object key", p.src[p.pos])
+		}
+		key := p.string()
+		if isError(key) {
+			return key
+		}
+		if err := p.expect(':'); err != nil {
+			return err
+		}
+		value := p.value()
+		if isError(value) {
+			return value
+		}
+		hash.Set(key.(*object.String), value)
+		p.skipSpace()
+		if p.pos < len(p.src) && p.src[p.pos] == '}' {
+			p.pos++
+			return hash
+		}
+		if err := p.expect(','); err != nil {
+			return err
+		}
+	}
+}
+
+func (p *jsonParser) array() object.Object {
+	p.pos++ // consume '['
+	array := &object.Array{}
+	p.skipSpace()
+	if p.pos < len(p.src) && p.src[p.pos] == ']' {
+		p.pos++
+		return array
+	}
+	for {
+		value := p.value()
+		if isError(value) {
+			return value
+		}
+		array.Elements = append(array.Elements, value)
+		p.skipSpace()
+		if p.pos < len(p.src) && p.src[p.pos] == ']' {
+			p.pos++
+			return array
+		}
+		if err := p.expect(','); err != nil {
+			return err
+		}
+	}
+}
+
+// string finds the end of the string starting at the current offset and
+// leaves decoding its escapes to encoding/json.
+func (p *jsonParser) string() object.Object {
+	start := p.pos
+	for i := start + 1; i < len(p.src); i++ {
+		switch p.src[i] {
+		case '\\':
+			i++
+		case '"':
+			var s string
+			if err := json.Unmarshal([]byte(p.src[start:i+1]), &s); err != nil {
+				return p.errorf("invalid string")
+			}
+			p.pos = i + 1
+			return &object.String{Value: s}
+		}
+	}
+	return p.errorf("unterminated string")
+}
+
+func (p *jsonParser) number() object.Object {
+	start := p.pos
+	end := start
+	for end < len(p.src) && strings.IndexByte("+-0123456789.eE", p.src[end]) >= 0 {
+		end++
+	}
+	literal := p.src[start:end]
+	if strings.ContainsAny(literal, ".eE") {
+		return p.errorf("number %s is not an integer", literal)
+	}
+	digits := strings.TrimPrefix(literal, "-")
+	if digits == "" || strings.Trim(digits, "0123456789") != "" || len(digits) > 1 && digits[0] == '0' {
+		return p.errorf("invalid number %s", literal)
+	}
+	value, _ := new(big.Int).SetString(literal, 10)
+	p.pos = end
+	return newInteger(value)
+}
+
+// jsonStringify encodes a value as JSON, indented by the given number of
+// spaces per level, or on one line when there is no indent or it is 0.
+func jsonStringify(env *object.Environment, args ...object.Object) object.Object {
+	if len(args) < 1 || len(args) > 2 {
+		return newError("wrong number of arguments. got=%d, want=1 or 2", len(args))
+	}
+	indent := 0
+	if len(args) == 2 {
+		i, ok := args[1].(*object.Integer)
+		if !ok || i.Value < 0 {
+			return newError("indent given to 'json_stringify' must be a non-negative INTEGER, got %s", args[1].Inspect())
+		}
+		indent = int(i.Value)
+	}
+	var out bytes.Buffer
+	if errObj := writeJSON(&out, args[0], "$"); errObj != nil {
+		return errObj
+	}
+	if indent > 0 {
+		var indented bytes.Buffer
+		json.Indent(&indented, out.Bytes(), "", strings.Repeat(" ", indent))
+		return &object.String{Value: indented.String()}
+	}
+	return &object.String{Value: out.String()}
+}
+
+// writeJSON writes obj to out. path locates obj in the value being
+// encoded, such as $["items"][2], for reporting values JSON cannot hold.
+func writeJSON(out *bytes.Buffer, obj object.Object, path string) *object.Error {
+	switch obj := obj.(type) {
+	case *object.Integer:
+		out.WriteString(strconv.FormatInt(obj.Value, 10))
+	case *object.BigInteger:
+		out.WriteString(obj.Value.String())
+	case *object.Bool:
+		out.WriteString(strconv.FormatBool(obj.Value))
+	case *object.Null, nil:
+		out.WriteString("null")
+	case *object.String:
+		writeJSONString(out, obj.Value)
+	case *object.Array:
+		out.WriteByte('[')
+		for i, e := range obj.Elements {
+			if i > 0 {
+				out.WriteByte(',')
+			}
+			if errObj := writeJSON(out, e, fmt.Sprintf("%s[%d]", path, i)); errObj != nil {
+				return errObj
+			}
+		}
+		out.WriteByte(']')
+	case *object.Hash:
+		out.WriteByte('{')
+		for i, pair := range obj.Pairs() {
+			key, ok := pair.Key.(*object.String)
+			if !ok {
+				return newError("json_stringify: key %s of the object at %s is %s, JSON keys must be STRING", pair.Key.Inspect(), path, pair.Key.Type())
+			}
+			if i > 0 {
+				out.WriteByte(',')
+			}
+			writeJSONString(out, key.Value)
+			out.WriteByte(':')
+			if errObj := writeJSON(out, pair.Value, path+"["+key.Inspect()+"]"); errObj != nil {
+				return errObj
+			}
+		}
+		out.WriteByte('}')
+	default:
+		return newError("json_stringify: %s at %s cannot be represented in JSON", obj.Type(), path)
+	}
+	return nil
+}
+
+func writeJSONString(out *bytes.Buffer, s string) {
+	out.WriteByte('"')
+	for _, r := range s {
+		switch {
+		case r == '"' || r == '\\':
+			out.WriteByte('\\')
+			out.WriteRune(r)
+		case r == '\n':
+			out.WriteString(`\n`)
+		case r == '\r':
+			out.WriteString(`\r`)
+		case r == '\t':
+			out.WriteString(`\t`)
+		case r < 0x20:
+			fmt.Fprintf(out, `\u%04x`, r)
+		case r == utf8.RuneError:
+			out.WriteString(`\ufffd`)
+		default:
+			out.WriteRune(r)
+		}
+	}
+	out.WriteByte('"')
+}
@@ -0,0 +1,184 @@
+package evaluator
+
+import (
+	"math/big"
+
+	"github.com/shozawa/monkey/object"
+)
+
+// Integers are Integers while they fit in 64 bits and BigIntegers once
+// they do not; the arithmetic here switches between the two as results
+// grow and shrink.
+
+func init() {
+	builtins["abs"] = &object.Builtin{Fn: abs}
+	builtins["min"] = &object.Builtin{Fn: func(env *object.Environment, args ...object.Object) object.Object {
+		return extreme("min", -1, args)
+	}}
+	builtins["max"] = &object.Builtin{Fn: func(env *object.Environment, args ...object.Object) object.Object {
+		return extreme("max", 1, args)
+	}}
+	builtins["pow"] = &object.Builtin{Fn: pow}
+	builtins["sqrt"] = &object.Builtin{Fn: sqrt}
+	builtins["gcd"] = &object.Builtin{Fn: gcd}
+	builtins["random"] = &object.Builtin{Capabilities: object.CAP_RANDOM, Fn: random}
+	builtins["seed"] = &object.Builtin{Capabilities: object.CAP_RANDOM, Fn: seed}
+}
+
+func isInteger(obj object.Object) bool {
+	switch obj.(type) {
+	case *object.Integer, *object.BigInteger:
+		return true
+	}
+	return false
+}
+
+// toBig returns the value of an Integer or BigInteger as a big.Int the
+// caller may modify.
+func toBig(obj object.Object) *big.Int {
+	switch obj := obj.(type) {
+	case *object.Integer:
+		return big.NewInt(obj.Value)
+	case *object.BigInteger:
+		return new(big.Int).Set(obj.Value)
+	}
+	return nil
+}
+
+// newInteger returns n as an Integer when it fits in one.
+func newInteger(n *big.Int) object.Object {
+	if n.IsInt64() {
+		return &object.Integer{Value: n.Int64()}
+	}
+	return &object.BigInteger{Value: n}
+}
+
+func evalBigIntegerInfixExpression(operator string, left, right *big.Int) object.Object {
+	switch operator {
+	case "+":
+		return newInteger(left.Add(left, right))
+	case "-":
+		return newInteger(left.Sub(left, right))
+	case "*":
+		return newInteger(left.Mul(left, right))
+	case "/":
+		if right.Sign() == 0 {
+			return newError("division by zero: %s / 0", left)
+		}
+		return newInteger(left.Quo(left, right))
+	case "%":
+		if right.Sign() == 0 {
+			return newError("division by zero: %s %% 0", left)
+		}
+		return newInteger(left.Rem(left, right))
+	case "==":
+		return nativeToBoolObject(left.Cmp(right) == 0)
+	case "!=":
+		return nativeToBoolObject(left.Cmp(right) != 0)
+	case "<":
+		return nativeToBoolObject(left.Cmp(right) < 0)
+	case ">":
+		return nativeToBoolObject(left.Cmp(right) > 0)
+	default:
+		return NULL
+	}
+}
+
+// integerArgs checks that args are n integers.
+func integerArgs(name string, args []object.Object, n int) ([]*big.Int, *object.Error) {
+	if len(args) != n {
+		return nil, newError("wrong number of arguments. got=%d, want=%d", len(args), n)
+	}
+	values := make([]*big.Int, n)
+	for i, arg := range args {
+		if !isInteger(arg) {
+			return nil, newError("argument to '%s' must be INTEGER, got %s", name, arg.Type())
+		}
+		values[i] = toBig(arg)
+	}
+	return values, nil
+}
+
+func abs(env *object.Environment, args ...object.Object) object.Object {
+	values, errObj := integerArgs("abs", args, 1)
+	if errObj != nil {
+		return errObj
+	}
+	return newInteger(values[0].Abs(values[0]))
+}
+
+// extreme returns the least of its arguments when sign is -1, and the
+// greatest when it is 1.
+func extreme(name string, sign int, args []object.Object) object.Object {
+	if len(args) == 0 {
+		return newError("wrong number of arguments. got=0, want=1 or more")
+	}
+	values, errObj := integerArgs(name, args, len(args))
+	if errObj != nil {
+		return errObj
+	}
+	result := 0
+	for i, value := range values {
+		if value.Cmp(values[result]) == sign {
+			result = i
+		}
+	}
+	return args[result]
+}
+
+func pow(env *object.Environment, args ...object.Object) object.Object {
+	values, errObj := integerArgs("pow", args, 2)
+	if errObj != nil {
+		return errObj
+	}
+	if values[1].Sign() < 0 {
+		return newError("exponent given to 'pow' must not be negative, got %s", values[1])
+	}
+	return newInteger(values[0].Exp(values[0], values[1], nil))
+}
+
+// sqrt returns the integer square root, rounding down.
+func sqrt(env *object.Environment, args ...object.Object) object.Object {
+	values, errObj := integerArgs("sqrt", args, 1)
+	if errObj != nil {
+		return errObj
+	}
+	if values[0].Sign() < 0 {
+		return newError("argument to 'sqrt' must not be negative, got %s", values[0])
+	}
+	return newInteger(values[0].Sqrt(values[0]))
+}
+
+// gcd returns the greatest common divisor of the absolute values of its
+// arguments, which is 0 only when both are.
+func gcd(env *object.Environment, args ...object.Object) object.Object {
+	values, errObj := integerArgs("gcd", args, 2)
+	if errObj != nil {
+		return errObj
+	}
+	return newInteger(new(big.Int).GCD(nil, nil, values[0], values[1]))
+}
+
+// random returns a random integer in [0, n).
+func random(env *object.Environment, args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+	n, ok := args[0].(*object.Integer)
+	if !ok || n.Value <= 0 {
+		return newError("argument to 'random' must be a positive INTEGER, got %s", args[0].Inspect())
+	}
+	return &object.Integer{Value: env.Runtime().Int63n(n.Value)}
+}
+
+func seed(env *object.Environment, args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+	n, ok := args[0].(*object.Integer)
+	if !ok {
+		return newError("argument to 'seed' must be INTEGER, got %s", args[0].Type())
+	}
+	env.Runtime().Seed(n.Value)
+	return NULL
+}
@@ -0,0 +1,92 @@
+package evaluator
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/shozawa/monkey/ast"
+	"github.com/shozawa/monkey/lexer"
+	"github.com/shozawa/monkey/object"
+	"github.com/shozawa/monkey/parser"
+)
+
+func init() {
+	builtins["import"] = &object.Builtin{
+		Capabilities: object.CAP_FS_READ,
+		Fn:           importModule,
+	}
+}
+
+func importModule(env *object.Environment, args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+	name, ok := args[0].(*object.String)
+	if !ok {
+		return newError("argument to 'import' must be STRING, got %s", args[0].Type())
+	}
+	importer := env.File()
+	path, err := resolveImport(importer, name.Value)
+	if err != nil {
+		return newError("import %q: %s", name.Value, err)
+	}
+	if importer.Imports(path) {
+		chain := importer.Chain()
+		for chain[0] != path {
+			chain = chain[1:]
+		}
+		chain = append(chain, path)
+		return newError("import cycle: %s", strings.Join(chain, " -> "))
+	}
+	rt := env.Runtime()
+	return rt.Modules().Load(rt, path, func() object.Object {
+		return loadModule(path, importer, rt)
+	})
+}
+
+// resolveImport turns the path given to import into an absolute one,
+// relative to the directory of the importing file.
+func resolveImport(importer *object.File, name string) (string, error) {
+	path := name
+	if !filepath.IsAbs(path) && importer != nil {
+		path = filepath.Join(filepath.Dir(importer.Path), path)
+	}
+	return filepath.Abs(path)
+}
+
+func loadModule(path string, importer *object.File, rt *object.Runtime) object.Object {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return newError("import %s: %s", path, err)
+	}
+	p := parser.New(lexer.New(string(src)))
+	program := p.Parse()
+	if errs := p.Errors(); len(errs) > 0 {
+		return newError("import %s: %s", path, strings.Join(errs, "; "))
+	}
+	env := object.NewEnvWithRuntime(rt)
+	env.SetFile(&object.File{Path: path, ImportedFrom: importer})
+	if result := Eval(&program, env); isError(result) {
+		return result
+	}
+	// Modules are shared by everyone importing them, so their
+	// bindings are read-only.
+	env.Freeze()
+	return &object.Module{Path: path, Env: env}
+}
+
+func evalMemberExpression(node *ast.MemberExpression, env *object.Environment) object.Object {
+	obj := Eval(node.Object, env)
+	if isError(obj) {
+		return obj
+	}
+	module, ok := obj.(*object.Module)
+	if !ok {
+		return newError("member access not supported: %s", obj.Type())
+	}
+	if value, ok := module.Env.GetLocal(node.Property.Value); ok {
+		return value
+	}
+	return newError("module %q has no binding %s", module.Path, node.Property.Value)
+}
@@ -0,0 +1,70 @@
+package evaluator
+
+import (
+	"os"
+	"strconv"
+
+	"github.com/shozawa/monkey/object"
+)
+
+// ARGS names the array of command-line arguments hosts bind for
+// scripts.
+const ARGS = "args"
+
+func init() {
+	builtins["getenv"] = &object.Builtin{Capabilities: object.CAP_ENV, Fn: getenv}
+	builtins["setenv"] = &object.Builtin{Capabilities: object.CAP_ENV, Fn: setenv}
+	builtins["exit"] = &object.Builtin{Fn: exit}
+}
+
+// NewArgs returns the value bound to ARGS for the arguments a script was
+// started with.
+func NewArgs(args []string) *object.Array {
+	elements := make([]object.Object, len(args))
+	for i, arg := range args {
+		elements[i] = &object.String{Value: arg}
+	}
+	return &object.Array{Elements: elements}
+}
+
+// getenv returns the value of an environment variable, or null when it
+// is not set.
+func getenv(env *object.Environment, args ...object.Object) object.Object {
+	values, errObj := stringArgs("getenv", args, 1)
+	if errObj != nil {
+		return errObj
+	}
+	value, ok := os.LookupEnv(values[0])
+	if !ok {
+		return NULL
+	}
+	return &object.String{Value: value}
+}
+
+func setenv(env *object.Environment, args ...object.Object) object.Object {
+	values, errObj := stringArgs("setenv", args, 2)
+	if errObj != nil {
+		return errObj
+	}
+	if err := os.Setenv(values[0], values[1]); err != nil {
+		return osError("setenv", err)
+	}
+	return NULL
+}
+
+// exit stops the evaluation by raising an error nothing catches, asking
+// the host to exit with the given status, 0 by default.
+func exit(env *object.Environment, args ...object.Object) object.Object {
+	if len(args) > 1 {
+		return newError("wrong number of arguments. got=%d, want=0 or 1", len(args))
+	}
+	code := 0
+	if len(args) == 1 {
+		i, ok := args[0].(*object.Integer)
+		if !ok {
+			return newError("argument to 'exit' must be INTEGER, got %s", args[0].Type())
+		}
+		code = int(i.Value)
+	}
+	return &object.Error{Message: "exit status " + strconv.Itoa(code), Exit: true, Code: code}
+}
@@ -0,0 +1,139 @@
+package evaluator
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/shozawa/monkey/object"
+)
+
+// Patterns use Go's RE2 syntax. Builtins take the regex first and the
+// string to search second.
+
+func init() {
+	builtins["regex"] = &object.Builtin{Fn: regex}
+	builtins["match"] = &object.Builtin{Fn: match}
+	builtins["find_all"] = &object.Builtin{Fn: findAll}
+	builtins["replace_all"] = &object.Builtin{Fn: replaceAll}
+	builtins["split"] = &object.Builtin{Fn: split}
+}
+
+func regex(env *object.Environment, args ...object.Object) object.Object {
+	values, errObj := stringArgs("regex", args, 1)
+	if errObj != nil {
+		return errObj
+	}
+	re, err := regexp.Compile(values[0])
+	if err != nil {
+		return newError("regex: %s", err)
+	}
+	return &object.Regex{Value: re}
+}
+
+// regexArgs checks that args are a regex followed by n-1 strings.
+func regexArgs(name string, args []object.Object, n int) (*regexp.Regexp, []string, *object.Error) {
+	if len(args) != n {
+		return nil, nil, newError("wrong number of arguments. got=%d, want=%d", len(args), n)
+	}
+	re, ok := args[0].(*object.Regex)
+	if !ok {
+		return nil, nil, newError("first argument to '%s' must be REGEX, got %s", name, args[0].Type())
+	}
+	values, errObj := stringArgs(name, args[1:], n-1)
+	if errObj != nil {
+		return nil, nil, errObj
+	}
+	return re.Value, values, nil
+}
+
+// submatches returns the text of the match at loc followed by that of
+// each group, with null for groups that took no part in it.
+func submatches(s string, loc []int) *object.Array {
+	elements := make([]object.Object, len(loc)/2)
+	for i := range elements {
+		if loc[2*i] < 0 {
+			elements[i] = NULL
+			continue
+		}
+		elements[i] = &object.String{Value: s[loc[2*i]:loc[2*i+1]]}
+	}
+	return &object.Array{Elements: elements}
+}
+
+// match returns the first match as an array of the matched text and its
+// groups, or null when there is none.
+func match(env *object.Environment, args ...object.Object) object.Object {
+	re, values, errObj := regexArgs("match", args, 2)
+	if errObj != nil {
+		return errObj
+	}
+	loc := re.FindStringSubmatchIndex(values[0])
+	if loc == nil {
+		return NULL
+	}
+	return submatches(values[0], loc)
+}
+
+func findAll(env *object.Environment, args ...object.Object) object.Object {
+	re, values, errObj := regexArgs("find_all", args, 2)
+	if errObj != nil {
+		return errObj
+	}
+	matches := re.FindAllString(values[0], -1)
+	elements := make([]object.Object, len(matches))
+	for i, m := range matches {
+		elements[i] = &object.String{Value: m}
+	}
+	return &object.Array{Elements: elements}
+}
+
+// replaceAll replaces every match with a string, in which $1 or ${name}
+// stand for groups, or with what a function returns when called with
+// the array match would return.
+func replaceAll(env *object.Environment, args ...object.Object) object.Object {
+	if len(args) != 3 {
+		return newError("wrong number of arguments. got=%d, want=3", len(args))
+	}
+	re, values, errObj := regexArgs("replace_all", args[:2], 2)
+	if errObj != nil {
+		return errObj
+	}
+	s := values[0]
+	switch repl := args[2].(type) {
+	case *object.String:
+		return &object.String{Value: re.ReplaceAllString(s, repl.Value)}
+	case *object.Function, *object.Builtin:
+		var out strings.Builder
+		last := 0
+		for _, loc := range re.FindAllStringSubmatchIndex(s, -1) {
+			result := applyFunction(repl, []object.Object{submatches(s, loc)}, env)
+			if isError(result) {
+				return result
+			}
+			str, ok := result.(*object.String)
+			if !ok {
+				return newError("replacement function given to 'replace_all' must return STRING, got %s", result.Type())
+			}
+			out.WriteString(s[last:loc[0]])
+			out.WriteString(str.Value)
+			last = loc[1]
+		}
+		out.WriteString(s[last:])
+		return &object.String{Value: out.String()}
+	default:
+		return newError("replacement given to 'replace_all' must be STRING or FUNCTION, got %s", args[2].Type())
+	}
+}
+
+func split(env *object.Environment, args ...object.Object) object.Object {
+	re, values, errObj := regexArgs("split", args, 2)
+	if errObj != nil {
+		return errObj
+	}
+	parts := re.Split(values[0], -1)
+	elements := make([]object.Object, len(parts))
+	for i, part := range parts {
+		elements[i] = &object.String{Value: part}
+	}
+	return &object.Array{Elements: elements}
+}
@@ -0,0 +1,178 @@
+package evaluator
+
+import (
+	"time"
+
+	"github.com/shozawa/monkey/object"
+)
+
+// Layouts are Go reference layouts such as "2006-01-02 15:04", or the
+// name of one in layouts. Durations are either strings Go can parse,
+// such as "1h30m", or a number of seconds.
+
+func init() {
+	builtins["now"] = &object.Builtin{Capabilities: object.CAP_CLOCK, Fn: now}
+	builtins["parse_time"] = &object.Builtin{Fn: parseTime}
+	builtins["format_time"] = &object.Builtin{Fn: formatTime}
+	builtins["add_duration"] = &object.Builtin{Fn: addDuration}
+	builtins["diff"] = &object.Builtin{Fn: timeDiff}
+	builtins["unix"] = &object.Builtin{Fn: unix}
+	builtins["from_unix"] = &object.Builtin{Fn: fromUnix}
+	builtins["in_zone"] = &object.Builtin{Fn: inZone}
+	builtins["zone"] = &object.Builtin{Fn: zone}
+}
+
+var layouts = map[string]string{
+	"RFC3339":  time.RFC3339,
+	"RFC1123":  time.RFC1123,
+	"DateTime": time.DateTime,
+	"DateOnly": time.DateOnly,
+	"TimeOnly": time.TimeOnly,
+	"Kitchen":  time.Kitchen,
+}
+
+func layout(s string) string {
+	if l, ok := layouts[s]; ok {
+		return l
+	}
+	return s
+}
+
+func timeArg(name string, arg object.Object) (time.Time, *object.Error) {
+	t, ok := arg.(*object.Time)
+	if !ok {
+		return time.Time{}, newError("argument to '%s' must be TIME, got %s", name, arg.Type())
+	}
+	return t.Value, nil
+}
+
+// now reads the runtime's clock, which hosts may replace.
+func now(env *object.Environment, args ...object.Object) object.Object {
+	if len(args) != 0 {
+		return newError("wrong number of arguments. got=%d, want=0", len(args))
+	}
+	return &object.Time{Value: env.Runtime().Now()}
+}
+
+// parseTime reads a time in UTC unless the string gives its offset.
+func parseTime(env *object.Environment, args ...object.Object) object.Object {
+	values, errObj := stringArgs("parse_time", args, 2)
+	if errObj != nil {
+		return errObj
+	}
+	t, err := time.Parse(layout(values[1]), values[0])
+	if err != nil {
+		return newError("parse_time: %s", err)
+	}
+	return &object.Time{Value: t}
+}
+
+func formatTime(env *object.Environment, args ...object.Object) object.Object {
+	if len(args) != 2 {
+		return newError("wrong number of arguments. got=%d, want=2", len(args))
+	}
+	t, errObj := timeArg("format_time", args[0])
+	if errObj != nil {
+		return errObj
+	}
+	values, errObj := stringArgs("format_time", args[1:], 1)
+	if errObj != nil {
+		return errObj
+	}
+	return &object.String{Value: t.Format(layout(values[0]))}
+}
+
+func addDuration(env *object.Environment, args ...object.Object) object.Object {
+	if len(args) != 2 {
+		return newError("wrong number of arguments. got=%d, want=2", len(args))
+	}
+	t, errObj := timeArg("add_duration", args[0])
+	if errObj != nil {
+		return errObj
+	}
+	var d time.Duration
+	switch arg := args[1].(type) {
+	case *object.Integer:
+		d = time.Duration(arg.Value) * time.Second
+	case *object.String:
+		var err error
+		if d, err = time.ParseDuration(arg.Value); err != nil {
+			return newError("add_duration: %s", err)
+		}
+	default:
+		return newError("duration given to 'add_duration' must be STRING or INTEGER, got %s", arg.Type())
+	}
+	return &object.Time{Value: t.Add(d)}
+}
+
+// timeDiff returns the seconds from b to a, dropping any fraction.
+func timeDiff(env *object.Environment, args ...object.Object) object.Object {
+	if len(args) != 2 {
+		return newError("wrong number of arguments. got=%d, want=2", len(args))
+	}
+	a, errObj := timeArg("diff", args[0])
+	if errObj != nil {
+		return errObj
+	}
+	b, errObj := timeArg("diff", args[1])
+	if errObj != nil {
+		return errObj
+	}
+	return &object.Integer{Value: int64(a.Sub(b) / time.Second)}
+}
+
+func unix(env *object.Environment, args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+	t, errObj := timeArg("unix", args[0])
+	if errObj != nil {
+		return errObj
+	}
+	return &object.Integer{Value: t.Unix()}
+}
+
+// fromUnix returns the time the given seconds after the Unix epoch, in
+// UTC.
+func fromUnix(env *object.Environment, args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+	seconds, ok := args[0].(*object.Integer)
+	if !ok {
+		return newError("argument to 'from_unix' must be INTEGER, got %s", args[0].Type())
+	}
+	return &object.Time{Value: time.Unix(seconds.Value, 0).UTC()}
+}
+
+// inZone returns the same instant in an IANA time zone such as
+// "Asia/Tokyo", or in "UTC" or "Local".
+func inZone(env *object.Environment, args ...object.Object) object.Object {
+	if len(args) != 2 {
+		return newError("wrong number of arguments. got=%d, want=2", len(args))
+	}
+	t, errObj := timeArg("in_zone", args[0])
+	if errObj != nil {
+		return errObj
+	}
+	values, errObj := stringArgs("in_zone", args[1:], 1)
+	if errObj != nil {
+		return errObj
+	}
+	loc, err := time.LoadLocation(values[0])
+	if err != nil {
+		return newError("in_zone: %s", err)
+	}
+	return &object.Time{Value: t.In(loc)}
+}
+
+func zone(env *object.Environment, args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+	t, errObj := timeArg("zone", args[0])
+	if errObj != nil {
+		return errObj
+	}
+	return &object.String{Value: t.Location().String()}
+}
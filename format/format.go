@@ -0,0 +1,30 @@
+package format
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+
+	"github.com/shozawa/monkey/lexer"
+	"github.com/shozawa/monkey/parser"
+	"github.com/shozawa/monkey/printer"
+)
+
+// Source formats src in the canonical layout: four-space indentation,
+// spaces around infix operators, opening braces on the same line and
+// statements terminated by semicolons. Comments are kept. Source
+// that does not parse is returned as an error, unformatted.
+func Source(src []byte) ([]byte, error) {
+	l := lexer.New(string(src))
+	p := parser.New(l)
+	program := p.Parse()
+	if errs := p.Errors(); len(errs) > 0 {
+		return nil, errors.New(strings.Join(errs, "\n"))
+	}
+	var buf bytes.Buffer
+	cfg := &printer.Config{Comments: l.Comments(), Source: string(src)}
+	if err := cfg.Fprint(&buf, &program); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,108 @@
+package format
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/shozawa/monkey/lexer"
+	"github.com/shozawa/monkey/parser"
+	"github.com/shozawa/monkey/printer"
+	"github.com/shozawa/monkey/token"
+)
+
+func TestSource(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"let x=1\nlet y=x*2", "let x = 1;\nlet y = x * 2;\n"},
+		{
+			"// header\n\n\nlet x = 1; // one\n\n// about f\nlet f = fn(a){ // body\n  a+x // sum\n  // done\n}\n// end\n",
+			"// header\n\nlet x = 1; // one\n\n// about f\nlet f = fn(a) { // body\n    a + x; // sum\n    // done\n};\n// end\n",
+		},
+		{"if (x) {\n// nothing yet\n}", "if (x) {\n    // nothing yet\n}\n"},
+		{"puts(1)\r\nputs(2)\r\n", "puts(1);\nputs(2);\n"},
+		{"let x = [1, // one\n  2];", "let x = [\n    1, // one\n    2\n];\n"},
+		{"f(a, // first\n// about b\nb)", "f(\n    a, // first\n    // about b\n    b\n);\n"},
+		{"let h = {\"a\": [1,\n2 // two\n], \"b\": 2};", "let h = {\n    \"a\": [\n        1,\n        2 // two\n    ],\n    \"b\": 2\n};\n"},
+	}
+	for _, test := range tests {
+		got, err := Source([]byte(test.input))
+		if err != nil {
+			t.Errorf("Source(%q) returned error: %s", test.input, err)
+			continue
+		}
+		if string(got) != test.want {
+			t.Errorf("Source(%q) not %q. got=%q", test.input, test.want, got)
+		}
+		again, _ := Source(got)
+		if string(again) != string(got) {
+			t.Errorf("formatting %q is not idempotent. got=%q", got, again)
+		}
+	}
+}
+
+func TestSourceWithSyntaxError(t *testing.T) {
+	if _, err := Source([]byte("let = 1;")); err == nil {
+		t.Error("Source did not report the syntax error")
+	}
+}
+
+func TestSourceKeepsMeaning(t *testing.T) {
+	inputs := []string{
+		"let a = true;\nif (a) { puts(1) };\n-1;",
+		"if (a) { 1 }\n;[x]\nif (a) { 1 } else { 2 };\n(f)(1)",
+		"let f = fn() { if (a) { 1 }; /x/ }; // regex\nf()",
+		"let x = [1, // one\n  2]; // x\nf(a, // first\n// about b\nb);",
+		"let h = {\"a\": 1, // a\n\"b\": [2 // two\n]};\n// end",
+	}
+	for _, input := range inputs {
+		formatted, err := Source([]byte(input))
+		if err != nil {
+			t.Errorf("Source(%q) returned error: %s", input, err)
+			continue
+		}
+		if got, want := parse(t, string(formatted)), parse(t, input); got != want {
+			t.Errorf("formatting %q changed its syntax tree.\nformatted: %q\nwant:      %s\ngot:       %s", input, formatted, want, got)
+		}
+		if got, want := commentPlaces(string(formatted)), commentPlaces(input); strings.Join(got, "\n") != strings.Join(want, "\n") {
+			t.Errorf("formatting %q moved comments.\nformatted: %q\nwant:      %q\ngot:       %q", input, formatted, want, got)
+		}
+	}
+}
+
+// parse returns src as printed from its syntax tree, which is the same
+// for the same tree.
+func parse(t *testing.T, src string) string {
+	p := parser.New(lexer.New(src))
+	program := p.Parse()
+	if errs := p.Errors(); len(errs) > 0 {
+		t.Fatalf("parser errors for %q: %v", src, errs)
+	}
+	return printer.Sprint(&program)
+}
+
+// commentPlaces describes each comment by the tokens before it,
+// leaving out the semicolons and parentheses formatting may add or
+// drop.
+func commentPlaces(src string) []string {
+	l := lexer.New(src)
+	var tokens []token.Token
+	for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+		switch tok.Type {
+		case token.SEMICOLON, token.LPAREN, token.RPAREN:
+		default:
+			tokens = append(tokens, tok)
+		}
+	}
+	var places []string
+	for _, comment := range l.Comments() {
+		n := 0
+		for n < len(tokens) && tokens[n].Position().Before(comment.Position()) {
+			n++
+		}
+		places = append(places, fmt.Sprintf("%s after %d tokens", comment.Literal, n))
+	}
+	return places
+}
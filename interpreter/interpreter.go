@@ -2,20 +2,109 @@ package interpreter
 
 import (
 	"bytes"
+	"fmt"
 	"io"
+	"os"
+	"path/filepath"
+	"time"
 
 	"github.com/shozawa/monkey/evaluator"
 	"github.com/shozawa/monkey/lexer"
 	"github.com/shozawa/monkey/object"
 	"github.com/shozawa/monkey/parser"
+	"github.com/shozawa/monkey/prelude"
 )
 
-func Execute(in io.Reader) {
+// Config adjusts how programs are run.
+type Config struct {
+	// Stdin is the input read_line and read_all read from.
+	Stdin io.Reader
+	// Args are the command-line arguments bound to args.
+	Args []string
+	// Clock, when set, is the clock now() reads instead of the system's.
+	Clock func() time.Time
+	// NoPrelude leaves out the prelude, the functions written in Monkey
+	// such as sum and reverse that otherwise enclose the program and
+	// every module it imports. The REPL, the test runner and the
+	// debugger take the same option.
+	NoPrelude bool
+	// Tracer, when set, observes the evaluation, as profilers do.
+	Tracer object.Tracer
+}
+
+// ExitError reports that the program called exit with a non-zero
+// status, or stopped with a runtime error, which has status 1.
+type ExitError struct {
+	Code int
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("exit status %d", e.Code)
+}
+
+func Execute(in io.Reader, out, errOut io.Writer) error {
+	return (&Config{}).Execute(in, out, errOut)
+}
+
+// ExecuteFile runs the program in path. Modules it imports are
+// resolved relative to the file.
+func ExecuteFile(path string, out, errOut io.Writer) error {
+	return (&Config{}).ExecuteFile(path, out, errOut)
+}
+
+func (c *Config) Execute(in io.Reader, out, errOut io.Writer) error {
 	buf := new(bytes.Buffer)
 	buf.ReadFrom(in)
-	code := buf.String()
+	return c.run(buf.String(), nil, out, errOut)
+}
+
+func (c *Config) ExecuteFile(path string, out, errOut io.Writer) error {
+	code, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	return c.run(string(code), &object.File{Path: abs}, out, errOut)
+}
+
+func (c *Config) run(code string, file *object.File, out, errOut io.Writer) error {
 	l := lexer.New(code)
 	p := parser.New(l)
 	program := p.Parse()
-	evaluator.Eval(&program, object.NewEnv())
+	if errs := p.ErrorList(); len(errs) > 0 {
+		for _, e := range errs {
+			if file != nil {
+				fmt.Fprintf(errOut, "%s:", file.Path)
+			}
+			fmt.Fprintln(errOut, e)
+		}
+		return &ExitError{Code: 1}
+	}
+	rt := &object.Runtime{
+		Capabilities: object.CAP_ALL,
+		Stdin:        c.Stdin,
+		Stdout:       out,
+		Stderr:       errOut,
+		Clock:        c.Clock,
+		Tracer:       c.Tracer,
+	}
+	if !c.NoPrelude {
+		rt.Prelude = prelude.Env()
+	}
+	env := object.NewEnvWithRuntime(rt)
+	env.SetFile(file)
+	env.Set(evaluator.ARGS, evaluator.NewArgs(c.Args))
+	result := evaluator.Eval(&program, env)
+	if errObj, ok := result.(*object.Error); ok {
+		if !errObj.Exit {
+			fmt.Fprintln(errOut, errObj.Inspect())
+			return &ExitError{Code: 1}
+		} else if errObj.Code != 0 {
+			return &ExitError{Code: errObj.Code}
+		}
+	}
+	return nil
 }
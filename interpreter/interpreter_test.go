@@ -0,0 +1,75 @@
+package interpreter
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExecuteFile(t *testing.T) {
+	tests := []struct {
+		code string
+		out  string
+		err  error
+	}{
+		{`puts(len(args)); puts(args[0]);`, "2\na\n", nil},
+		{`puts(1); exit(0); puts(2);`, "1\n", nil},
+		{`puts(1); exit(3); puts(2);`, "1\n", &ExitError{Code: 3}},
+		{`puts(unix(now()));`, "86400\n", nil},
+		{`puts(sum(map(args, len)));`, "2\n", nil},
+	}
+	for _, test := range tests {
+		path := filepath.Join(t.TempDir(), "main.monkey")
+		if err := os.WriteFile(path, []byte(test.code), 0644); err != nil {
+			t.Fatal(err)
+		}
+		var out, errOut bytes.Buffer
+		config := &Config{
+			Args:  []string{"a", "b"},
+			Clock: func() time.Time { return time.Unix(86400, 0) },
+		}
+		err := config.ExecuteFile(path, &out, &errOut)
+		if (err == nil) != (test.err == nil) || err != nil && err.Error() != test.err.Error() {
+			t.Errorf("%s: error not %v. got=%v", test.code, test.err, err)
+		}
+		if out.String() != test.out {
+			t.Errorf("%s: output not %q. got=%q", test.code, test.out, out.String())
+		}
+		if errOut.String() != "" {
+			t.Errorf("%s: errOut not empty. got=%q", test.code, errOut.String())
+		}
+	}
+}
+
+func TestNoPrelude(t *testing.T) {
+	var out, errOut bytes.Buffer
+	config := &Config{NoPrelude: true}
+	err := config.Execute(strings.NewReader("sum([1])"), &out, &errOut)
+	if exitErr, ok := err.(*ExitError); !ok || exitErr.Code != 1 {
+		t.Errorf("error not exit status 1. got=%v", err)
+	}
+	if want := "ERROR: identifier not found: sum\n"; errOut.String() != want {
+		t.Errorf("errOut not %q. got=%q", want, errOut.String())
+	}
+}
+
+func TestSyntaxError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "main.monkey")
+	if err := os.WriteFile(path, []byte("let x = 1 +;\nputs(5)"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var out, errOut bytes.Buffer
+	err := (&Config{}).ExecuteFile(path, &out, &errOut)
+	if exitErr, ok := err.(*ExitError); !ok || exitErr.Code != 1 {
+		t.Errorf("error not exit status 1. got=%v", err)
+	}
+	if out.String() != "" {
+		t.Errorf("program ran. out=%q", out.String())
+	}
+	if want := path + ":1:12: "; !strings.HasPrefix(errOut.String(), want) {
+		t.Errorf("errOut does not start with %q. got=%q", want, errOut.String())
+	}
+}
@@ -1,6 +1,8 @@
 package lexer
 
 import (
+	"strings"
+
 	"github.com/shozawa/monkey/token"
 )
 
@@ -9,16 +11,30 @@ type Lexer struct {
 	position     int
 	readPosition int
 	ch           byte
+	line         int
+	column       int
+	comments     []token.Token
+	prev         token.TokenType
 }
 
 func New(input string) *Lexer {
-	l := &Lexer{input: input}
+	l := &Lexer{input: input, line: 1}
 	l.readChar()
 	return l
 }
 
+// Comments returns the comments skipped so far, as COMMENT tokens.
+func (l *Lexer) Comments() []token.Token {
+	return l.comments
+}
+
 func (l *Lexer) NextToken() (tok token.Token) {
 	l.skipWhitespace()
+	line, column := l.line, l.column
+	defer func() {
+		tok.Line, tok.Column = line, column
+		l.prev = tok.Type
+	}()
 	switch l.ch {
 	case '=':
 		if l.peek() == '=' {
@@ -45,6 +61,10 @@ func (l *Lexer) NextToken() (tok token.Token) {
 	case '*':
 		tok = newToken(token.ASTERISK, l.ch)
 	case '/':
+		if !endsOperand[l.prev] {
+			tok = l.readRegexLiteral()
+			return
+		}
 		tok = newToken(token.SLASH, l.ch)
 	case '%':
 		tok = newToken(token.PARCENT, l.ch)
@@ -56,10 +76,18 @@ func (l *Lexer) NextToken() (tok token.Token) {
 		tok = newToken(token.LBRACE, l.ch)
 	case '}':
 		tok = newToken(token.RBRACE, l.ch)
+	case '[':
+		tok = newToken(token.LBRACKET, l.ch)
+	case ']':
+		tok = newToken(token.RBRACKET, l.ch)
 	case ',':
 		tok = newToken(token.COMMA, l.ch)
 	case ';':
 		tok = newToken(token.SEMICOLON, l.ch)
+	case ':':
+		tok = newToken(token.COLON, l.ch)
+	case '.':
+		tok = newToken(token.DOT, l.ch)
 	case '<':
 		tok = newToken(token.LT, l.ch)
 	case '>':
@@ -74,7 +102,8 @@ func (l *Lexer) NextToken() (tok token.Token) {
 		} else if isLetter(l.ch) {
 			tok = l.readIdentifier()
 		} else {
-			tok = token.Token{Type: token.ILLEGAL, Literal: ""}
+			tok = newToken(token.ILLEGAL, l.ch)
+			l.readChar()
 		}
 		return
 	}
@@ -83,12 +112,34 @@ func (l *Lexer) NextToken() (tok token.Token) {
 }
 
 func (l *Lexer) skipWhitespace() {
-	for l.ch == ' ' || l.ch == '\t' || l.ch == '\n' {
+	for {
+		switch {
+		case l.ch == ' ' || l.ch == '\t' || l.ch == '\n' || l.ch == '\r':
+			l.readChar()
+		case l.ch == '/' && l.peek() == '/':
+			l.readComment()
+		default:
+			return
+		}
+	}
+}
+
+func (l *Lexer) readComment() {
+	position := l.position
+	comment := token.Token{Type: token.COMMENT, Line: l.line, Column: l.column}
+	for l.ch != '\n' && l.ch != 0 {
 		l.readChar()
 	}
+	comment.Literal = strings.TrimRight(l.input[position:l.position], "\r")
+	l.comments = append(l.comments, comment)
 }
 
 func (l *Lexer) readChar() {
+	if l.ch == '\n' {
+		l.line++
+		l.column = 0
+	}
+	l.column++
 	if l.readPosition >= len(l.input) {
 		l.ch = 0
 	} else {
@@ -124,15 +175,85 @@ func (l *Lexer) readInteger() token.Token {
 	return token.Token{Type: token.INT, Literal: i}
 }
 
+var escapes = map[byte]byte{
+	'n':  '\n',
+	't':  '\t',
+	'r':  '\r',
+	'"':  '"',
+	'\\': '\\',
+}
+
+// readStringLiteral decodes the escape sequences \n, \t, \r, \" and \\.
+// It returns an ILLEGAL token holding the opening quote and the rest of
+// the input when the string is never closed.
 func (l *Lexer) readStringLiteral() token.Token {
 	l.readChar() // consume "
 	position := l.position
+	var s []byte
 	for l.ch != '"' {
+		if l.ch == 0 {
+			return token.Token{Type: token.ILLEGAL, Literal: l.input[position-1:]}
+		}
+		if l.ch == '\\' {
+			if ch, ok := escapes[l.peek()]; ok {
+				l.readChar()
+				s = append(s, ch)
+				l.readChar()
+				continue
+			}
+		}
+		s = append(s, l.ch)
 		l.readChar()
 	}
-	s := l.input[position:l.position]
 	l.readChar() // consume "
-	return token.Token{Type: token.STRING, Literal: s}
+	return token.Token{Type: token.STRING, Literal: string(s)}
+}
+
+// endsOperand holds the tokens an operand can end with. A slash after
+// one of them divides; anywhere else it starts a regex literal. The
+// lexer cannot tell a brace closing a function or hash literal from one
+// closing a block statement, so a slash after any "}" divides: a regex
+// literal starting the statement after a block, as in
+//
+//	let f = fn() { 1 }
+//	/a/;
+//
+// needs a semicolon after the brace.
+var endsOperand = map[token.TokenType]bool{
+	token.IDENT:    true,
+	token.INT:      true,
+	token.STRING:   true,
+	token.REGEX:    true,
+	token.TRUE:     true,
+	token.FALSE:    true,
+	token.RPAREN:   true,
+	token.RBRACKET: true,
+	token.RBRACE:   true,
+}
+
+// readRegexLiteral reads a pattern between slashes, where \/ stands for
+// a slash and other escapes are left for the regex syntax. It returns
+// an ILLEGAL token holding the rest of the line when the pattern is
+// never closed.
+func (l *Lexer) readRegexLiteral() token.Token {
+	l.readChar() // consume /
+	position := l.position
+	var s []byte
+	for l.ch != '/' {
+		if l.ch == 0 || l.ch == '\n' {
+			return token.Token{Type: token.ILLEGAL, Literal: l.input[position-1 : l.position]}
+		}
+		if l.ch == '\\' && l.peek() == '/' {
+			l.readChar()
+		} else if l.ch == '\\' && l.peek() != 0 && l.peek() != '\n' {
+			s = append(s, l.ch)
+			l.readChar()
+		}
+		s = append(s, l.ch)
+		l.readChar()
+	}
+	l.readChar() // consume /
+	return token.Token{Type: token.REGEX, Literal: string(s)}
 }
 
 func newToken(tokenType token.TokenType, ch byte) token.Token {
@@ -16,6 +16,8 @@ func TestNextToken(t *testing.T) {
 	1 == 1;
 	1 != 1;
 	!false;
+	[1, 2][0];
+	{"a": 1};
 	`
 	tests := []struct {
 		expectedType    token.TokenType
@@ -68,6 +70,23 @@ func TestNextToken(t *testing.T) {
 		{token.BANG, "!"},
 		{token.FALSE, "false"},
 		{token.SEMICOLON, ";"},
+		// [1, 2][0];
+		{token.LBRACKET, "["},
+		{token.INT, "1"},
+		{token.COMMA, ","},
+		{token.INT, "2"},
+		{token.RBRACKET, "]"},
+		{token.LBRACKET, "["},
+		{token.INT, "0"},
+		{token.RBRACKET, "]"},
+		{token.SEMICOLON, ";"},
+		// {"a": 1};
+		{token.LBRACE, "{"},
+		{token.STRING, "a"},
+		{token.COLON, ":"},
+		{token.INT, "1"},
+		{token.RBRACE, "}"},
+		{token.SEMICOLON, ";"},
 		{token.EOF, ""},
 	}
 	l := New(input)
@@ -90,13 +109,49 @@ func TestConsumeStringLiteral(t *testing.T) {
 		{
 			`let str = "hello";`,
 			[]token.Token{
-				token.Token{Type: token.LET, Literal: "let"},
-				token.Token{Type: token.IDENT, Literal: "str"},
-				token.Token{Type: token.ASSIGN, Literal: "="},
-				token.Token{Type: token.STRING, Literal: "hello"},
-				token.Token{Type: token.SEMICOLON, Literal: ";"},
+				token.Token{Type: token.LET, Literal: "let", Line: 1, Column: 1},
+				token.Token{Type: token.IDENT, Literal: "str", Line: 1, Column: 5},
+				token.Token{Type: token.ASSIGN, Literal: "=", Line: 1, Column: 9},
+				token.Token{Type: token.STRING, Literal: "hello", Line: 1, Column: 11},
+				token.Token{Type: token.SEMICOLON, Literal: ";", Line: 1, Column: 18},
 			},
 		},
+		{
+			`"say \"hi\"\n\ttab \\ \q"`,
+			[]token.Token{
+				token.Token{Type: token.STRING, Literal: "say \"hi\"\n\ttab \\ \\q", Line: 1, Column: 1},
+				token.Token{Type: token.EOF, Literal: "", Line: 1, Column: 26},
+			},
+		},
+	}
+	for _, test := range tests {
+		l := New(test.input)
+		for _, want := range test.want {
+			tok := l.NextToken()
+			if tok != want {
+				t.Errorf("tok is not %v. got=%v", want, tok)
+			}
+		}
+	}
+}
+
+func TestIllegalTokens(t *testing.T) {
+	tests := []struct {
+		input string
+		want  []token.Token
+	}{
+		{`1 @ 2`, []token.Token{
+			token.Token{Type: token.INT, Literal: "1", Line: 1, Column: 1},
+			token.Token{Type: token.ILLEGAL, Literal: "@", Line: 1, Column: 3},
+			token.Token{Type: token.INT, Literal: "2", Line: 1, Column: 5},
+			token.Token{Type: token.EOF, Literal: "", Line: 1, Column: 6},
+		}},
+		{`puts("abc`, []token.Token{
+			token.Token{Type: token.IDENT, Literal: "puts", Line: 1, Column: 1},
+			token.Token{Type: token.LPAREN, Literal: "(", Line: 1, Column: 5},
+			token.Token{Type: token.ILLEGAL, Literal: `"abc`, Line: 1, Column: 6},
+			token.Token{Type: token.EOF, Literal: "", Line: 1, Column: 10},
+		}},
 	}
 	for _, test := range tests {
 		l := New(test.input)
@@ -109,6 +164,109 @@ func TestConsumeStringLiteral(t *testing.T) {
 	}
 }
 
+func TestRegexLiteral(t *testing.T) {
+	tests := []struct {
+		input string
+		want  []token.Token
+	}{
+		{`match(/a\/b\d+/, s) / 2`, []token.Token{
+			{Type: token.IDENT, Literal: "match", Line: 1, Column: 1},
+			{Type: token.LPAREN, Literal: "(", Line: 1, Column: 6},
+			{Type: token.REGEX, Literal: `a/b\d+`, Line: 1, Column: 7},
+			{Type: token.COMMA, Literal: ",", Line: 1, Column: 16},
+			{Type: token.IDENT, Literal: "s", Line: 1, Column: 18},
+			{Type: token.RPAREN, Literal: ")", Line: 1, Column: 19},
+			{Type: token.SLASH, Literal: "/", Line: 1, Column: 21},
+			{Type: token.INT, Literal: "2", Line: 1, Column: 23},
+		}},
+		{`let r = /x/; xs[0] / /y/`, []token.Token{
+			{Type: token.LET, Literal: "let", Line: 1, Column: 1},
+			{Type: token.IDENT, Literal: "r", Line: 1, Column: 5},
+			{Type: token.ASSIGN, Literal: "=", Line: 1, Column: 7},
+			{Type: token.REGEX, Literal: "x", Line: 1, Column: 9},
+			{Type: token.SEMICOLON, Literal: ";", Line: 1, Column: 12},
+			{Type: token.IDENT, Literal: "xs", Line: 1, Column: 14},
+			{Type: token.LBRACKET, Literal: "[", Line: 1, Column: 16},
+			{Type: token.INT, Literal: "0", Line: 1, Column: 17},
+			{Type: token.RBRACKET, Literal: "]", Line: 1, Column: 18},
+			{Type: token.SLASH, Literal: "/", Line: 1, Column: 20},
+			{Type: token.REGEX, Literal: "y", Line: 1, Column: 22},
+		}},
+		{"return /a\\\\/\n1", []token.Token{
+			{Type: token.RETURN, Literal: "return", Line: 1, Column: 1},
+			{Type: token.REGEX, Literal: `a\\`, Line: 1, Column: 8},
+			{Type: token.INT, Literal: "1", Line: 2, Column: 1},
+		}},
+		{"f(/ab\n)", []token.Token{
+			{Type: token.IDENT, Literal: "f", Line: 1, Column: 1},
+			{Type: token.LPAREN, Literal: "(", Line: 1, Column: 2},
+			{Type: token.ILLEGAL, Literal: "/ab", Line: 1, Column: 3},
+			{Type: token.RPAREN, Literal: ")", Line: 2, Column: 1},
+		}},
+		// After a closing brace a slash always divides, even when the
+		// brace ends a statement; a semicolon starts the regex.
+		{"{ 1 }\n/a/", []token.Token{
+			{Type: token.LBRACE, Literal: "{", Line: 1, Column: 1},
+			{Type: token.INT, Literal: "1", Line: 1, Column: 3},
+			{Type: token.RBRACE, Literal: "}", Line: 1, Column: 5},
+			{Type: token.SLASH, Literal: "/", Line: 2, Column: 1},
+			{Type: token.IDENT, Literal: "a", Line: 2, Column: 2},
+			{Type: token.SLASH, Literal: "/", Line: 2, Column: 3},
+		}},
+		{"{ 1 };\n/a/", []token.Token{
+			{Type: token.LBRACE, Literal: "{", Line: 1, Column: 1},
+			{Type: token.INT, Literal: "1", Line: 1, Column: 3},
+			{Type: token.RBRACE, Literal: "}", Line: 1, Column: 5},
+			{Type: token.SEMICOLON, Literal: ";", Line: 1, Column: 6},
+			{Type: token.REGEX, Literal: "a", Line: 2, Column: 1},
+		}},
+	}
+	for _, test := range tests {
+		l := New(test.input)
+		for _, want := range test.want {
+			tok := l.NextToken()
+			if tok != want {
+				t.Errorf("%q: tok is not %v. got=%v", test.input, want, tok)
+			}
+		}
+	}
+}
+
+func TestPositionsAndComments(t *testing.T) {
+	input := "// header\nlet x = 1; // one\r\n\n  x / 2 // two"
+	wantTokens := []token.Token{
+		{Type: token.LET, Literal: "let", Line: 2, Column: 1},
+		{Type: token.IDENT, Literal: "x", Line: 2, Column: 5},
+		{Type: token.ASSIGN, Literal: "=", Line: 2, Column: 7},
+		{Type: token.INT, Literal: "1", Line: 2, Column: 9},
+		{Type: token.SEMICOLON, Literal: ";", Line: 2, Column: 10},
+		{Type: token.IDENT, Literal: "x", Line: 4, Column: 3},
+		{Type: token.SLASH, Literal: "/", Line: 4, Column: 5},
+		{Type: token.INT, Literal: "2", Line: 4, Column: 7},
+		{Type: token.EOF, Literal: "", Line: 4, Column: 15},
+	}
+	wantComments := []token.Token{
+		{Type: token.COMMENT, Literal: "// header", Line: 1, Column: 1},
+		{Type: token.COMMENT, Literal: "// one", Line: 2, Column: 12},
+		{Type: token.COMMENT, Literal: "// two", Line: 4, Column: 9},
+	}
+	l := New(input)
+	for _, want := range wantTokens {
+		if tok := l.NextToken(); tok != want {
+			t.Errorf("tok is not %v. got=%v", want, tok)
+		}
+	}
+	comments := l.Comments()
+	if len(comments) != len(wantComments) {
+		t.Fatalf("len(comments) not %d. got=%d", len(wantComments), len(comments))
+	}
+	for i, want := range wantComments {
+		if comments[i] != want {
+			t.Errorf("comments[%d] is not %v. got=%v", i, want, comments[i])
+		}
+	}
+}
+
 func TestIsLetter(t *testing.T) {
 	tests := []struct {
 		input byte
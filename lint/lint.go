@@ -0,0 +1,344 @@
+package lint
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/shozawa/monkey/ast"
+	"github.com/shozawa/monkey/evaluator"
+	"github.com/shozawa/monkey/lexer"
+	"github.com/shozawa/monkey/parser"
+	"github.com/shozawa/monkey/prelude"
+	"github.com/shozawa/monkey/token"
+)
+
+// Rule IDs, as printed with each issue and accepted by lint:ignore.
+const (
+	UNUSED_BINDING   = "unused-binding"
+	SHADOWED_NAME    = "shadowed-name"
+	ARG_COUNT        = "arg-count"
+	UNREACHABLE_CODE = "unreachable-code"
+	IF_WITHOUT_ELSE  = "if-without-else"
+)
+
+var Rules = []string{UNUSED_BINDING, SHADOWED_NAME, ARG_COUNT, UNREACHABLE_CODE, IF_WITHOUT_ELSE}
+
+// IGNORE_DIRECTIVE in a comment suppresses the listed rules on the
+// comment's line and the line after it:
+//
+//	let f = fn(x) { x }; // lint:ignore shadowed-name
+const IGNORE_DIRECTIVE = "lint:ignore"
+
+type Issue struct {
+	Pos     token.Position
+	Rule    string
+	Message string
+}
+
+func (i Issue) String() string {
+	return fmt.Sprintf("%s: %s (%s)", i.Pos, i.Message, i.Rule)
+}
+
+type Config struct {
+	// Disabled rules are not reported anywhere.
+	Disabled map[string]bool
+}
+
+// Source parses src and returns the issues found in it, sorted by
+// position. Source that does not parse is returned as an error.
+func (c *Config) Source(src []byte) ([]Issue, error) {
+	l := lexer.New(string(src))
+	p := parser.New(l)
+	program := p.Parse()
+	if errs := p.Errors(); len(errs) > 0 {
+		return nil, errors.New(strings.Join(errs, "\n"))
+	}
+	return c.Check(&program, l.Comments()), nil
+}
+
+// Check lints program. Comments carrying lint:ignore directives
+// suppress issues next to them.
+func (c *Config) Check(program *ast.Program, comments []token.Token) []Issue {
+	l := &linter{used: make(map[*ast.FunctionLiteral]bool)}
+	l.scope = l.openScope(nil, false)
+	l.statements(program.Statements, false)
+
+	ignored := ignoredRules(comments)
+	var issues []Issue
+	for _, issue := range l.issues {
+		if c.Disabled[issue.Rule] || ignored[issue.Pos.Line][issue.Rule] {
+			continue
+		}
+		issues = append(issues, issue)
+	}
+	sort.SliceStable(issues, func(i, j int) bool {
+		return issues[i].Pos.Before(issues[j].Pos)
+	})
+	return issues
+}
+
+// ignoredRules maps each line to the rules suppressed on it.
+func ignoredRules(comments []token.Token) map[int]map[string]bool {
+	ignored := make(map[int]map[string]bool)
+	for _, comment := range comments {
+		text := strings.TrimSpace(strings.TrimPrefix(comment.Literal, "//"))
+		if !strings.HasPrefix(text, IGNORE_DIRECTIVE) {
+			continue
+		}
+		rules := strings.FieldsFunc(strings.TrimPrefix(text, IGNORE_DIRECTIVE), func(r rune) bool {
+			return r == ',' || r == ' '
+		})
+		for _, line := range []int{comment.Line, comment.Line + 1} {
+			if ignored[line] == nil {
+				ignored[line] = make(map[string]bool)
+			}
+			for _, rule := range rules {
+				ignored[line][rule] = true
+			}
+		}
+	}
+	return ignored
+}
+
+type binding struct {
+	name  string
+	pos   token.Position
+	param bool
+	used  bool
+	fn    *ast.FunctionLiteral
+}
+
+// scope holds the bindings of one function call, or of the program.
+// Blocks of if expressions share the enclosing scope, as they do when
+// evaluated.
+type scope struct {
+	parent   *scope
+	local    bool
+	bindings map[string]*binding
+	order    []*binding
+}
+
+func (s *scope) lookup(name string) *binding {
+	for ; s != nil; s = s.parent {
+		if b, ok := s.bindings[name]; ok {
+			return b
+		}
+	}
+	return nil
+}
+
+type linter struct {
+	scope  *scope
+	issues []Issue
+	// used holds the functions whose results are known to be used.
+	used map[*ast.FunctionLiteral]bool
+}
+
+func (l *linter) report(pos token.Position, rule, format string, a ...interface{}) {
+	l.issues = append(l.issues, Issue{Pos: pos, Rule: rule, Message: fmt.Sprintf(format, a...)})
+}
+
+func (l *linter) openScope(parent *scope, local bool) *scope {
+	return &scope{parent: parent, local: local, bindings: make(map[string]*binding)}
+}
+
+// closeScope reports the let bindings of a function that were never
+// used. Top-level bindings are left alone since importers may use them.
+func (l *linter) closeScope() {
+	s := l.scope
+	if s.local {
+		for _, b := range s.order {
+			if !b.used && !b.param && !strings.HasPrefix(b.name, "_") {
+				l.report(b.pos, UNUSED_BINDING, "%s is bound but never used", b.name)
+			}
+		}
+	}
+	l.scope = s.parent
+}
+
+func (l *linter) declare(ident *ast.Identifier, param bool, fn *ast.FunctionLiteral) {
+	name := ident.Value
+	pos := ident.Token.Position()
+	what := "binding"
+	if param {
+		what = "parameter"
+	}
+	if _, ok := l.scope.bindings[name]; !ok {
+		if outer := l.scope.parent.lookup(name); outer != nil {
+			l.report(pos, SHADOWED_NAME, "%s %s shadows the binding at %s", what, name, outer.pos)
+		} else if evaluator.IsBuiltin(name) {
+			l.report(pos, SHADOWED_NAME, "%s %s shadows the builtin function", what, name)
+		} else if _, ok := prelude.Env().GetLocal(name); ok {
+			l.report(pos, SHADOWED_NAME, "%s %s shadows the prelude binding", what, name)
+		}
+	}
+	b := &binding{name: name, pos: pos, param: param, fn: fn}
+	l.scope.bindings[name] = b
+	l.scope.order = append(l.scope.order, b)
+}
+
+// statements walks stmts. value tells whether the result of the last
+// one is used.
+func (l *linter) statements(stmts []ast.Statement, value bool) {
+	for i, stmt := range stmts {
+		l.statement(stmt, value && i == len(stmts)-1)
+		if _, ok := stmt.(*ast.ReturnStatement); ok && i+1 < len(stmts) {
+			l.report(ast.Pos(stmts[i+1]), UNREACHABLE_CODE, "unreachable code after return")
+			for j, rest := range stmts[i+1:] {
+				l.statement(rest, value && i+1+j == len(stmts)-1)
+			}
+			return
+		}
+	}
+}
+
+func (l *linter) statement(stmt ast.Statement, value bool) {
+	switch stmt := stmt.(type) {
+	case *ast.LetStatement:
+		// A function may refer to its own name, so bind it first.
+		if fn, ok := stmt.Value.(*ast.FunctionLiteral); ok {
+			l.declare(stmt.Name, false, fn)
+			l.expression(stmt.Value, true)
+			return
+		}
+		l.expression(stmt.Value, true)
+		l.declare(stmt.Name, false, nil)
+	case *ast.ReturnStatement:
+		if stmt.ReturnValue != nil {
+			l.expression(stmt.ReturnValue, true)
+		}
+	case *ast.ExpressionStatement:
+		l.expression(stmt.Expression, value)
+	case *ast.BlockStatement:
+		l.statements(stmt.Statements, value)
+	}
+}
+
+// expression walks exp. value tells whether its result is used.
+func (l *linter) expression(exp ast.Expression, value bool) {
+	switch exp := exp.(type) {
+	case *ast.Identifier:
+		if b := l.scope.lookup(exp.Value); b != nil {
+			b.used = true
+		}
+	case *ast.PrefixExpression:
+		l.expression(exp.Right, true)
+	case *ast.Infix:
+		l.expression(exp.Left, true)
+		l.expression(exp.Right, true)
+	case *ast.IfExpression:
+		if value && exp.Alternative == nil {
+			l.report(exp.Token.Position(), IF_WITHOUT_ELSE, "if without else used as a value is null when the condition is false")
+		}
+		l.expression(exp.Condition, true)
+		l.statements(exp.Consequence.Statements, value)
+		if exp.Alternative != nil {
+			l.statements(exp.Alternative.Statements, value)
+		}
+	case *ast.FunctionLiteral:
+		l.scope = l.openScope(l.scope, true)
+		for _, param := range exp.Parameters {
+			l.declare(param, true, nil)
+		}
+		// Whether the value of the body is used depends on the calls,
+		// which resultUsed checks.
+		l.statements(exp.Body.Statements, false)
+		l.closeScope()
+	case *ast.CallExpression:
+		l.expression(exp.Function, true)
+		if value {
+			l.resultUsed(l.function(exp.Function))
+		}
+		for _, arg := range exp.Arguments {
+			l.expression(arg, true)
+			// Callbacks are called for their results.
+			l.resultUsed(l.function(arg))
+		}
+		l.checkArgCount(exp)
+	case *ast.MemberExpression:
+		l.expression(exp.Object, true)
+	case *ast.ArrayLiteral:
+		for _, e := range exp.Elements {
+			l.expression(e, true)
+		}
+	case *ast.IndexExpression:
+		l.expression(exp.Left, true)
+		l.expression(exp.Index, true)
+	case *ast.HashLiteral:
+		for _, pair := range exp.Pairs {
+			l.expression(pair.Key, true)
+			l.expression(pair.Value, true)
+		}
+	}
+}
+
+// function returns the function literal exp is or is bound to, if
+// known.
+func (l *linter) function(exp ast.Expression) *ast.FunctionLiteral {
+	switch exp := exp.(type) {
+	case *ast.FunctionLiteral:
+		return exp
+	case *ast.Identifier:
+		if b := l.scope.lookup(exp.Value); b != nil {
+			return b.fn
+		}
+	}
+	return nil
+}
+
+// resultUsed reports the ifs without else that fn returns the value of,
+// once its result is known to be used.
+func (l *linter) resultUsed(fn *ast.FunctionLiteral) {
+	if fn == nil || l.used[fn] {
+		return
+	}
+	l.used[fn] = true
+	for _, ie := range tailIfs(fn.Body.Statements) {
+		l.report(ie.Token.Position(), IF_WITHOUT_ELSE, "if without else used as a value is null when the condition is false")
+	}
+}
+
+// tailIfs returns the ifs without else whose value is the value of
+// stmts.
+func tailIfs(stmts []ast.Statement) []*ast.IfExpression {
+	if len(stmts) == 0 {
+		return nil
+	}
+	stmt, ok := stmts[len(stmts)-1].(*ast.ExpressionStatement)
+	if !ok {
+		return nil
+	}
+	ie, ok := stmt.Expression.(*ast.IfExpression)
+	if !ok {
+		return nil
+	}
+	var ifs []*ast.IfExpression
+	if ie.Alternative == nil {
+		ifs = append(ifs, ie)
+	} else {
+		ifs = append(ifs, tailIfs(ie.Alternative.Statements)...)
+	}
+	return append(ifs, tailIfs(ie.Consequence.Statements)...)
+}
+
+func (l *linter) checkArgCount(call *ast.CallExpression) {
+	var fn *ast.FunctionLiteral
+	name := "function"
+	switch callee := call.Function.(type) {
+	case *ast.FunctionLiteral:
+		fn = callee
+	case *ast.Identifier:
+		if b := l.scope.lookup(callee.Value); b != nil {
+			fn = b.fn
+			name = callee.Value
+		}
+	}
+	if fn == nil {
+		return
+	}
+	if want, got := len(fn.Parameters), len(call.Arguments); want != got {
+		l.report(ast.Pos(call), ARG_COUNT, "%s takes %d arguments but is called with %d", name, want, got)
+	}
+}
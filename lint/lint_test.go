@@ -0,0 +1,102 @@
+package lint
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRules(t *testing.T) {
+	tests := []struct {
+		input string
+		want  []string
+	}{
+		{"let f = fn(x) { let y = x; x };", []string{
+			"1:21: y is bound but never used (unused-binding)",
+		}},
+		{"let f = fn(x) { let _y = x; x };", nil},
+		{"let unused = 1;", nil},
+		{"let f = fn() { let n = 1; if (true) { n } else { 0 } };", nil},
+		{"let x = 1; let f = fn(x) { x };", []string{
+			"1:23: parameter x shadows the binding at 1:5 (shadowed-name)",
+		}},
+		{"let f = fn() { let len = 1; len };", []string{
+			"1:20: binding len shadows the builtin function (shadowed-name)",
+		}},
+		{"let x = 1; let x = 2;", nil},
+		{"let add = fn(a, b) { a + b }; add(1);", []string{
+			"1:31: add takes 2 arguments but is called with 1 (arg-count)",
+		}},
+		{"fn(a) { a }(1, 2);", []string{
+			"1:1: function takes 1 arguments but is called with 2 (arg-count)",
+		}},
+		{"let fact = fn(n) { if (n < 2) { 1 } else { n * fact(n - 1, 0) } };", []string{
+			"1:48: fact takes 1 arguments but is called with 2 (arg-count)",
+		}},
+		{"let f = fn() { return 1; puts(2); puts(3) };", []string{
+			"1:26: unreachable code after return (unreachable-code)",
+		}},
+		{"let x = if (true) { 1 };", []string{
+			"1:9: if without else used as a value is null when the condition is false (if-without-else)",
+		}},
+		{"puts(if (true) { 1 });", []string{
+			"1:6: if without else used as a value is null when the condition is false (if-without-else)",
+		}},
+		{"if (true) { puts(1) }", nil},
+		{"let sum = 1; puts(sum);", []string{
+			"1:5: binding sum shadows the prelude binding (shadowed-name)",
+		}},
+		{"let f = fn(x) { if (x) { 1 } }; puts(f(1));", []string{
+			"1:17: if without else used as a value is null when the condition is false (if-without-else)",
+		}},
+		{"let f = fn(x) { if (x) { if (x) { 1 } } else { 2 } }; let y = f(1);", []string{
+			"1:26: if without else used as a value is null when the condition is false (if-without-else)",
+		}},
+		{"map([1], fn(x) { if (x) { 1 } });", []string{
+			"1:18: if without else used as a value is null when the condition is false (if-without-else)",
+		}},
+		{"let log = fn(x) { if (x) { puts(x) } }; log(1);", nil},
+		{"let f = fn(x) { if (x) { puts(x) }; x }; f(1);", nil},
+	}
+	for _, test := range tests {
+		issues, err := (&Config{}).Source([]byte(test.input))
+		if err != nil {
+			t.Errorf("Source(%q) returned error: %s", test.input, err)
+			continue
+		}
+		var got []string
+		for _, issue := range issues {
+			got = append(got, issue.String())
+		}
+		if strings.Join(got, "\n") != strings.Join(test.want, "\n") {
+			t.Errorf("Source(%q) not %q. got=%q", test.input, test.want, got)
+		}
+	}
+}
+
+func TestSuppression(t *testing.T) {
+	input := `let x = 1;
+let f = fn(x) { x }; // lint:ignore shadowed-name
+// lint:ignore unused-binding, shadowed-name
+let g = fn(x) { let y = 1; x };
+let h = fn() { let z = 1; 0 };
+`
+	issues, err := (&Config{}).Source([]byte(input))
+	if err != nil {
+		t.Fatalf("Source returned error: %s", err)
+	}
+	if len(issues) != 1 || issues[0].Rule != UNUSED_BINDING || issues[0].Pos.Line != 5 {
+		t.Errorf("issues not only the unused z on line 5. got=%v", issues)
+	}
+
+	config := &Config{Disabled: map[string]bool{UNUSED_BINDING: true}}
+	issues, _ = config.Source([]byte(input))
+	if len(issues) != 0 {
+		t.Errorf("disabled rule was reported. got=%v", issues)
+	}
+}
+
+func TestSyntaxError(t *testing.T) {
+	if _, err := (&Config{}).Source([]byte("let = 1;")); err == nil {
+		t.Error("Source did not report the syntax error")
+	}
+}
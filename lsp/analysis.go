@@ -0,0 +1,275 @@
+package lsp
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/shozawa/monkey/ast"
+	"github.com/shozawa/monkey/lexer"
+	"github.com/shozawa/monkey/object"
+	"github.com/shozawa/monkey/parser"
+	"github.com/shozawa/monkey/prelude"
+	"github.com/shozawa/monkey/token"
+)
+
+// definition is a let binding or a function parameter.
+type definition struct {
+	name *ast.Identifier
+	// value is the bound expression of a let, nil for a parameter or
+	// for a let still being typed.
+	value ast.Expression
+	param bool
+	// fn is the function the binding is local to, nil at top level.
+	fn *ast.FunctionLiteral
+}
+
+// document is an open file and what was learnt from parsing it.
+type document struct {
+	uri      string
+	text     string
+	program  ast.Program
+	comments []token.Token
+	errors   []parser.Error
+
+	defs []*definition
+	// refs resolves every identifier, including the names being
+	// defined, to its definition. Builtins and unknown names are absent.
+	refs   map[*ast.Identifier]*definition
+	idents []*ast.Identifier
+}
+
+func newDocument(uri, text string) *document {
+	l := lexer.New(text)
+	p := parser.New(l)
+	d := &document{uri: uri, text: text, refs: make(map[*ast.Identifier]*definition)}
+	d.program = p.Parse()
+	d.comments = l.Comments()
+	d.errors = p.ErrorList()
+	r := &resolver{doc: d, scope: &scope{names: make(map[string]*definition)}}
+	r.statements(d.program.Statements)
+	return d
+}
+
+type scope struct {
+	parent *scope
+	fn     *ast.FunctionLiteral
+	names  map[string]*definition
+}
+
+func (s *scope) lookup(name string) *definition {
+	for ; s != nil; s = s.parent {
+		if def, ok := s.names[name]; ok {
+			return def
+		}
+	}
+	return nil
+}
+
+// resolver walks the program binding names the way the evaluator
+// does: functions get a scope of their own, if blocks do not.
+type resolver struct {
+	doc   *document
+	scope *scope
+}
+
+func (r *resolver) define(name *ast.Identifier, value ast.Expression, param bool) {
+	def := &definition{name: name, value: value, param: param, fn: r.scope.fn}
+	r.scope.names[name.Value] = def
+	r.doc.defs = append(r.doc.defs, def)
+	r.doc.refs[name] = def
+	r.doc.idents = append(r.doc.idents, name)
+}
+
+func (r *resolver) statements(stmts []ast.Statement) {
+	for _, stmt := range stmts {
+		switch stmt := stmt.(type) {
+		case *ast.LetStatement:
+			// A function may call itself by the name it is bound to.
+			if _, ok := stmt.Value.(*ast.FunctionLiteral); ok {
+				r.define(stmt.Name, stmt.Value, false)
+				r.expression(stmt.Value)
+				continue
+			}
+			r.expression(stmt.Value)
+			r.define(stmt.Name, stmt.Value, false)
+		case *ast.ReturnStatement:
+			r.expression(stmt.ReturnValue)
+		case *ast.ExpressionStatement:
+			r.expression(stmt.Expression)
+		case *ast.BlockStatement:
+			r.statements(stmt.Statements)
+		}
+	}
+}
+
+func (r *resolver) expression(exp ast.Expression) {
+	switch exp := exp.(type) {
+	case *ast.Identifier:
+		if def := r.scope.lookup(exp.Value); def != nil {
+			r.doc.refs[exp] = def
+		}
+		r.doc.idents = append(r.doc.idents, exp)
+	case *ast.PrefixExpression:
+		r.expression(exp.Right)
+	case *ast.Infix:
+		r.expression(exp.Left)
+		r.expression(exp.Right)
+	case *ast.IfExpression:
+		r.expression(exp.Condition)
+		r.statements(exp.Consequence.Statements)
+		if exp.Alternative != nil {
+			r.statements(exp.Alternative.Statements)
+		}
+	case *ast.FunctionLiteral:
+		r.scope = &scope{parent: r.scope, fn: exp, names: make(map[string]*definition)}
+		for _, param := range exp.Parameters {
+			r.define(param, nil, true)
+		}
+		r.statements(exp.Body.Statements)
+		r.scope = r.scope.parent
+	case *ast.CallExpression:
+		r.expression(exp.Function)
+		for _, arg := range exp.Arguments {
+			r.expression(arg)
+		}
+	case *ast.MemberExpression:
+		r.expression(exp.Object)
+	case *ast.ArrayLiteral:
+		for _, e := range exp.Elements {
+			r.expression(e)
+		}
+	case *ast.IndexExpression:
+		r.expression(exp.Left)
+		r.expression(exp.Index)
+	case *ast.HashLiteral:
+		for _, pair := range exp.Pairs {
+			r.expression(pair.Key)
+			r.expression(pair.Value)
+		}
+	}
+}
+
+// identAt returns the identifier covering pos, or nil.
+func (d *document) identAt(pos token.Position) *ast.Identifier {
+	for _, ident := range d.idents {
+		start := ident.Token.Position()
+		if start.Line == pos.Line && start.Column <= pos.Column && pos.Column <= start.Column+len(ident.Value) {
+			return ident
+		}
+	}
+	return nil
+}
+
+// visible returns the definitions in scope at pos, innermost first.
+func (d *document) visible(pos token.Position) []*definition {
+	var defs []*definition
+	seen := make(map[string]bool)
+	for i := len(d.defs) - 1; i >= 0; i-- {
+		def := d.defs[i]
+		if seen[def.name.Value] || def.fn != nil && !contains(def.fn, pos) {
+			continue
+		}
+		seen[def.name.Value] = true
+		defs = append(defs, def)
+	}
+	return defs
+}
+
+func contains(fn *ast.FunctionLiteral, pos token.Position) bool {
+	return !pos.Before(fn.Token.Position()) && !fn.Body.Rbrace.Position().Before(pos)
+}
+
+func identRange(ident *ast.Identifier) Range {
+	start := toPosition(ident.Token.Position())
+	end := start
+	end.Character += len(ident.Value)
+	return Range{Start: start, End: end}
+}
+
+// describe is the hover text for def.
+func (d *document) describe(def *definition) string {
+	if def.param {
+		return fmt.Sprintf("parameter %s of %s", def.name.Value, signature(def.fn))
+	}
+	if def.value == nil {
+		return "let " + def.name.Value
+	}
+	switch value := def.value.(type) {
+	case *ast.FunctionLiteral:
+		return fmt.Sprintf("let %s: %s %s", def.name.Value, object.FUNCTION_OBJ, signature(value))
+	case *ast.IntegerLiteral, *ast.BigIntegerLiteral, *ast.StringLiteral, *ast.BoolLiteral, *ast.RegexLiteral:
+		return fmt.Sprintf("let %s: %s = %s", def.name.Value, d.typeOf(value, nil), value)
+	}
+	if t := d.typeOf(def.value, map[*definition]bool{def: true}); t != "" {
+		return fmt.Sprintf("let %s: %s", def.name.Value, t)
+	}
+	return "let " + def.name.Value
+}
+
+// describePrelude describes a binding of the prelude, which every
+// program sees, or returns "" if there is none called name.
+func describePrelude(name string) string {
+	value, ok := prelude.Env().Get(name)
+	if !ok {
+		return ""
+	}
+	if fn, ok := value.(*object.Function); ok {
+		params := make([]string, len(fn.Parameters))
+		for i, param := range fn.Parameters {
+			params[i] = param.Value
+		}
+		return fmt.Sprintf("prelude %s: %s fn(%s)", name, object.FUNCTION_OBJ, strings.Join(params, ", "))
+	}
+	return fmt.Sprintf("prelude %s: %s", name, value.Type())
+}
+
+func signature(fn *ast.FunctionLiteral) string {
+	params := make([]string, len(fn.Parameters))
+	for i, param := range fn.Parameters {
+		params[i] = param.Value
+	}
+	return fmt.Sprintf("fn(%s)", strings.Join(params, ", "))
+}
+
+// typeOf infers the type exp evaluates to, or "" when that depends on
+// values only known at run time. seen guards against bindings that
+// refer to themselves.
+func (d *document) typeOf(exp ast.Expression, seen map[*definition]bool) object.ObjectType {
+	switch exp := exp.(type) {
+	case *ast.Identifier:
+		if def := d.refs[exp]; def != nil && def.value != nil && !seen[def] {
+			seen[def] = true
+			return d.typeOf(def.value, seen)
+		}
+	case *ast.IntegerLiteral:
+		return object.INTEGER_OBJ
+	case *ast.BigIntegerLiteral:
+		return object.BIG_INTEGER_OBJ
+	case *ast.StringLiteral:
+		return object.STRING_OBJ
+	case *ast.RegexLiteral:
+		return object.REGEX_OBJ
+	case *ast.BoolLiteral:
+		return object.BOOL_OBJ
+	case *ast.FunctionLiteral:
+		return object.FUNCTION_OBJ
+	case *ast.ArrayLiteral:
+		return object.ARRAY_OBJ
+	case *ast.HashLiteral:
+		return object.HASH_OBJ
+	case *ast.PrefixExpression:
+		if exp.Operator == "!" {
+			return object.BOOL_OBJ
+		}
+		return object.INTEGER_OBJ
+	case *ast.Infix:
+		switch exp.Operator {
+		case "==", "!=", "<", ">":
+			return object.BOOL_OBJ
+		}
+		if d.typeOf(exp.Left, seen) == object.INTEGER_OBJ && d.typeOf(exp.Right, seen) == object.INTEGER_OBJ {
+			return object.INTEGER_OBJ
+		}
+	}
+	return ""
+}
@@ -0,0 +1,210 @@
+package lsp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+const testURI = "file:///test.monkey"
+
+const testSource = `let x = 5;
+let add = fn(a, b) {
+    let total = a + b;
+    total
+};
+add(x, 1);
+let y = x * 2;
+`
+
+// session runs a server over the given messages, then returns the
+// messages it wrote, keyed by request id or notification method.
+func session(t *testing.T, msgs ...string) map[string]json.RawMessage {
+	var in bytes.Buffer
+	for _, msg := range msgs {
+		fmt.Fprintf(&in, "Content-Length: %d\r\n\r\n%s", len(msg), msg)
+	}
+	var out bytes.Buffer
+	if err := NewServer(&in, &out).Run(); err != nil {
+		t.Fatalf("Run returned error: %s", err)
+	}
+	got := make(map[string]json.RawMessage)
+	c := &conn{in: bufio.NewReader(&out)}
+	for {
+		body, err := c.read()
+		if err != nil {
+			break
+		}
+		var msg struct {
+			ID     json.RawMessage `json:"id"`
+			Method string          `json:"method"`
+			Result json.RawMessage `json:"result"`
+			Params json.RawMessage `json:"params"`
+		}
+		json.Unmarshal(body, &msg)
+		if msg.Method != "" {
+			got[msg.Method] = msg.Params
+		} else {
+			got[string(msg.ID)] = msg.Result
+		}
+	}
+	return got
+}
+
+func open(text string) string {
+	doc, _ := json.Marshal(TextDocumentItem{URI: testURI, Text: text})
+	return fmt.Sprintf(`{"jsonrpc":"2.0","method":"textDocument/didOpen","params":{"textDocument":%s}}`, doc)
+}
+
+func call(id int, method string, line, character int) string {
+	return fmt.Sprintf(`{"jsonrpc":"2.0","id":%d,"method":%q,"params":{"textDocument":{"uri":%q},"position":{"line":%d,"character":%d}}}`,
+		id, method, testURI, line, character)
+}
+
+const shutdown = `{"jsonrpc":"2.0","id":99,"method":"shutdown"}`
+const exit = `{"jsonrpc":"2.0","method":"exit"}`
+
+func TestHoverOnPartialDocument(t *testing.T) {
+	got := session(t, open("let add = ;\nadd;"),
+		call(1, "textDocument/hover", 1, 1),
+		call(2, "textDocument/completion", 1, 3),
+		shutdown, exit)
+	var hover Hover
+	json.Unmarshal(got["1"], &hover)
+	if !strings.Contains(hover.Contents.Value, "let add") {
+		t.Errorf("hover not %q. got=%s", "let add", got["1"])
+	}
+	if !strings.Contains(string(got["2"]), `"label":"add"`) {
+		t.Errorf("completion does not offer add. got=%s", got["2"])
+	}
+}
+
+func TestDiagnostics(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{testSource, `[]`},
+		{"let x = ;", `[{"range":{"start":{"line":0,"character":8},"end":{"line":0,"character":9}},"severity":1,"source":"monkey","message":"no prefix parse function for SEMICOLON \";\" found"}]`},
+		{"let f = fn(x) { let y = 1; x };", `[{"range":{"start":{"line":0,"character":20},"end":{"line":0,"character":21}},"severity":2,"code":"unused-binding","source":"monkey lint","message":"y is bound but never used"}]`},
+	}
+	for _, test := range tests {
+		got := session(t, open(test.input), shutdown, exit)
+		var params publishDiagnosticsParams
+		json.Unmarshal(got["textDocument/publishDiagnostics"], &params)
+		diagnostics, _ := json.Marshal(params.Diagnostics)
+		if string(diagnostics) != test.want {
+			t.Errorf("diagnostics for %q not %s. got=%s", test.input, test.want, diagnostics)
+		}
+	}
+}
+
+func TestHoverAndDefinition(t *testing.T) {
+	got := session(t, open(testSource),
+		call(1, "textDocument/hover", 5, 4),
+		call(2, "textDocument/hover", 3, 5),
+		call(3, "textDocument/hover", 2, 20),
+		call(4, "textDocument/definition", 5, 0),
+		call(5, "textDocument/definition", 3, 4),
+		call(6, "textDocument/definition", 2, 20),
+		call(7, "textDocument/hover", 4, 0),
+		call(8, "textDocument/hover", 6, 4),
+		shutdown, exit)
+
+	hovers := map[string]string{
+		"1": "let x: INTEGER = 5",
+		"2": "let total\n",
+		"3": "parameter b of fn(a, b)",
+		"8": "let y: INTEGER\n",
+	}
+	for id, want := range hovers {
+		var hover Hover
+		json.Unmarshal(got[id], &hover)
+		if !strings.Contains(hover.Contents.Value, want) {
+			t.Errorf("hover %s not %q. got=%q", id, want, hover.Contents.Value)
+		}
+	}
+	if string(got["7"]) != "null" {
+		t.Errorf("hover outside an identifier not null. got=%s", got["7"])
+	}
+
+	definitions := map[string]Position{
+		"4": {Line: 1, Character: 4},
+		"5": {Line: 2, Character: 8},
+		"6": {Line: 1, Character: 16},
+	}
+	for id, want := range definitions {
+		var loc Location
+		json.Unmarshal(got[id], &loc)
+		if loc.URI != testURI || loc.Range.Start != want {
+			t.Errorf("definition %s not at %v. got=%s", id, want, got[id])
+		}
+	}
+}
+
+func TestHoverOnPrelude(t *testing.T) {
+	got := session(t, open("reverse([1]);"), call(1, "textDocument/hover", 0, 2), shutdown, exit)
+	var hover Hover
+	json.Unmarshal(got["1"], &hover)
+	if want := "prelude reverse: FUNCTION fn(xs)"; !strings.Contains(hover.Contents.Value, want) {
+		t.Errorf("hover not %q. got=%s", want, got["1"])
+	}
+}
+
+func TestCompletion(t *testing.T) {
+	got := session(t, open(testSource),
+		call(1, "textDocument/completion", 3, 4),
+		call(2, "textDocument/completion", 5, 0),
+		shutdown, exit)
+
+	labels := func(id string) string {
+		var items []CompletionItem
+		json.Unmarshal(got[id], &items)
+		var names []string
+		for _, item := range items {
+			names = append(names, item.Label)
+		}
+		return strings.Join(names, " ")
+	}
+	if l := labels("1"); !strings.HasPrefix(l, "y total b a add x ") || !strings.Contains(l, "len") {
+		t.Errorf("completion inside add not locals, globals and builtins. got=%q", l)
+	}
+	if l := labels("2"); !strings.HasPrefix(l, "y add x ") {
+		t.Errorf("completion at top level not globals only. got=%q", l)
+	}
+	if l := labels("2"); !strings.Contains(l, " reverse ") {
+		t.Errorf("completion does not offer the prelude once. got=%q", l)
+	}
+}
+
+func TestDocumentSymbolsAndFormatting(t *testing.T) {
+	symbols := `{"jsonrpc":"2.0","id":1,"method":"textDocument/documentSymbol","params":{"textDocument":{"uri":"` + testURI + `"}}}`
+	formatting := `{"jsonrpc":"2.0","id":2,"method":"textDocument/formatting","params":{"textDocument":{"uri":"` + testURI + `"},"options":{}}}`
+	got := session(t, open("let x=1\nlet f = fn(a) { let b = a; b }"), symbols, formatting, shutdown, exit)
+
+	var syms []DocumentSymbol
+	json.Unmarshal(got["1"], &syms)
+	if len(syms) != 2 || syms[0].Name != "x" || syms[1].Name != "f" || syms[1].Kind != SYMBOL_FUNCTION {
+		t.Fatalf("symbols not x and f. got=%s", got["1"])
+	}
+	if len(syms[1].Children) != 1 || syms[1].Children[0].Name != "b" {
+		t.Errorf("f's children not b. got=%+v", syms[1].Children)
+	}
+
+	var edits []TextEdit
+	json.Unmarshal(got["2"], &edits)
+	want := "let x = 1;\nlet f = fn(a) {\n    let b = a;\n    b;\n};\n"
+	if len(edits) != 1 || edits[0].NewText != want || edits[0].Range.End != (Position{Line: 1, Character: 30}) {
+		t.Errorf("formatting edit not the whole document. got=%s", got["2"])
+	}
+}
+
+func TestExitWithoutShutdown(t *testing.T) {
+	in := fmt.Sprintf("Content-Length: %d\r\n\r\n%s", len(exit), exit)
+	if err := NewServer(strings.NewReader(in), &bytes.Buffer{}).Run(); err != ErrNoShutdown {
+		t.Errorf("Run not ErrNoShutdown. got=%v", err)
+	}
+}
@@ -0,0 +1,153 @@
+package lsp
+
+import (
+	"encoding/json"
+
+	"github.com/shozawa/monkey/token"
+)
+
+// The subset of the Language Server Protocol the server speaks. Field
+// names follow the specification.
+
+type request struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id,omitempty"`
+	Method  string           `json:"method"`
+	Params  json.RawMessage  `json:"params,omitempty"`
+}
+
+type response struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id"`
+	Result  interface{}      `json:"result"`
+	Error   *responseError   `json:"error,omitempty"`
+}
+
+type notification struct {
+	JSONRPC string      `json:"jsonrpc"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params"`
+}
+
+type responseError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+const (
+	CODE_PARSE_ERROR      = -32700
+	CODE_INVALID_PARAMS   = -32602
+	CODE_METHOD_NOT_FOUND = -32601
+)
+
+// Position is zero-based, unlike token.Position. Monkey source is
+// ASCII, so characters are counted in bytes.
+type Position struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+func toPosition(pos token.Position) Position {
+	return Position{Line: pos.Line - 1, Character: pos.Column - 1}
+}
+
+func (p Position) token() token.Position {
+	return token.Position{Line: p.Line + 1, Column: p.Character + 1}
+}
+
+type Range struct {
+	Start Position `json:"start"`
+	End   Position `json:"end"`
+}
+
+type Location struct {
+	URI   string `json:"uri"`
+	Range Range  `json:"range"`
+}
+
+type TextDocumentItem struct {
+	URI  string `json:"uri"`
+	Text string `json:"text"`
+}
+
+type TextDocumentIdentifier struct {
+	URI string `json:"uri"`
+}
+
+type didOpenParams struct {
+	TextDocument TextDocumentItem `json:"textDocument"`
+}
+
+type didChangeParams struct {
+	TextDocument   TextDocumentIdentifier `json:"textDocument"`
+	ContentChanges []struct {
+		Text string `json:"text"`
+	} `json:"contentChanges"`
+}
+
+type documentParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+}
+
+type positionParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+	Position     Position               `json:"position"`
+}
+
+const (
+	SEVERITY_ERROR   = 1
+	SEVERITY_WARNING = 2
+)
+
+type Diagnostic struct {
+	Range    Range  `json:"range"`
+	Severity int    `json:"severity"`
+	Code     string `json:"code,omitempty"`
+	Source   string `json:"source"`
+	Message  string `json:"message"`
+}
+
+type publishDiagnosticsParams struct {
+	URI         string       `json:"uri"`
+	Diagnostics []Diagnostic `json:"diagnostics"`
+}
+
+type MarkupContent struct {
+	Kind  string `json:"kind"`
+	Value string `json:"value"`
+}
+
+type Hover struct {
+	Contents MarkupContent `json:"contents"`
+	Range    Range         `json:"range"`
+}
+
+const (
+	COMPLETION_FUNCTION = 3
+	COMPLETION_VARIABLE = 6
+)
+
+type CompletionItem struct {
+	Label  string `json:"label"`
+	Kind   int    `json:"kind"`
+	Detail string `json:"detail,omitempty"`
+}
+
+const (
+	SYMBOL_FUNCTION = 12
+	SYMBOL_VARIABLE = 13
+)
+
+type DocumentSymbol struct {
+	Name           string           `json:"name"`
+	Detail         string           `json:"detail,omitempty"`
+	Kind           int              `json:"kind"`
+	Range          Range            `json:"range"`
+	SelectionRange Range            `json:"selectionRange"`
+	Children       []DocumentSymbol `json:"children,omitempty"`
+}
+
+type TextEdit struct {
+	Range   Range  `json:"range"`
+	NewText string `json:"newText"`
+}
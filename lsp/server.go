@@ -0,0 +1,332 @@
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+
+	"github.com/shozawa/monkey/ast"
+	"github.com/shozawa/monkey/evaluator"
+	"github.com/shozawa/monkey/format"
+	"github.com/shozawa/monkey/lint"
+	"github.com/shozawa/monkey/object"
+	"github.com/shozawa/monkey/prelude"
+)
+
+// Server answers LSP requests for the documents an editor has open.
+// Requests are handled one at a time, in the order they arrive.
+type Server struct {
+	conn     *conn
+	docs     map[string]*document
+	shutdown bool
+}
+
+type handler func(s *Server, params json.RawMessage) (interface{}, error)
+
+var handlers map[string]handler
+
+func init() {
+	handlers = map[string]handler{
+		"initialize":                  (*Server).initialize,
+		"shutdown":                    (*Server).shutdownRequest,
+		"textDocument/didOpen":        (*Server).didOpen,
+		"textDocument/didChange":      (*Server).didChange,
+		"textDocument/didClose":       (*Server).didClose,
+		"textDocument/hover":          (*Server).hover,
+		"textDocument/definition":     (*Server).definition,
+		"textDocument/completion":     (*Server).completion,
+		"textDocument/documentSymbol": (*Server).documentSymbol,
+		"textDocument/formatting":     (*Server).formatting,
+	}
+}
+
+func NewServer(in io.Reader, out io.Writer) *Server {
+	return &Server{
+		conn: &conn{in: bufio.NewReader(in), out: out},
+		docs: make(map[string]*document),
+	}
+}
+
+// Run serves until the client sends exit or closes the input. It
+// returns ErrNoShutdown if the client exits without asking the server to
+// shut down first.
+func (s *Server) Run() error {
+	for {
+		body, err := s.conn.read()
+		if err != nil {
+			if err == io.EOF && s.shutdown {
+				return nil
+			}
+			return err
+		}
+		var req request
+		if err := json.Unmarshal(body, &req); err != nil {
+			s.conn.write(response{JSONRPC: "2.0", Error: &responseError{CODE_PARSE_ERROR, err.Error()}})
+			continue
+		}
+		if req.Method == "exit" {
+			if !s.shutdown {
+				return ErrNoShutdown
+			}
+			return nil
+		}
+		if err := s.handle(&req); err != nil {
+			return err
+		}
+	}
+}
+
+var ErrNoShutdown = errors.New("exit before shutdown")
+
+func (s *Server) handle(req *request) error {
+	h, ok := handlers[req.Method]
+	if req.ID == nil {
+		// Notifications get no response, even when unknown.
+		if ok {
+			h(s, req.Params)
+		}
+		return nil
+	}
+	resp := response{JSONRPC: "2.0", ID: req.ID}
+	if !ok {
+		resp.Error = &responseError{CODE_METHOD_NOT_FOUND, "method not found: " + req.Method}
+		return s.conn.write(resp)
+	}
+	result, err := h(s, req.Params)
+	if err != nil {
+		resp.Error = &responseError{CODE_INVALID_PARAMS, err.Error()}
+	}
+	resp.Result = result
+	return s.conn.write(resp)
+}
+
+func (s *Server) initialize(params json.RawMessage) (interface{}, error) {
+	return map[string]interface{}{
+		"capabilities": map[string]interface{}{
+			"textDocumentSync":           1, // the full text on every change
+			"hoverProvider":              true,
+			"definitionProvider":         true,
+			"completionProvider":         map[string]interface{}{},
+			"documentSymbolProvider":     true,
+			"documentFormattingProvider": true,
+		},
+		"serverInfo": map[string]string{"name": "monkey"},
+	}, nil
+}
+
+func (s *Server) shutdownRequest(params json.RawMessage) (interface{}, error) {
+	s.shutdown = true
+	return nil, nil
+}
+
+func (s *Server) didOpen(params json.RawMessage) (interface{}, error) {
+	var p didOpenParams
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, err
+	}
+	return nil, s.update(p.TextDocument.URI, p.TextDocument.Text)
+}
+
+func (s *Server) didChange(params json.RawMessage) (interface{}, error) {
+	var p didChangeParams
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, err
+	}
+	if len(p.ContentChanges) == 0 {
+		return nil, nil
+	}
+	return nil, s.update(p.TextDocument.URI, p.ContentChanges[len(p.ContentChanges)-1].Text)
+}
+
+func (s *Server) didClose(params json.RawMessage) (interface{}, error) {
+	var p documentParams
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, err
+	}
+	delete(s.docs, p.TextDocument.URI)
+	return nil, s.publish(p.TextDocument.URI, []Diagnostic{})
+}
+
+// update reparses a document and publishes its diagnostics: syntax
+// errors, or lint warnings once it parses.
+func (s *Server) update(uri, text string) error {
+	doc := newDocument(uri, text)
+	s.docs[uri] = doc
+	diagnostics := []Diagnostic{}
+	for _, err := range doc.errors {
+		diagnostics = append(diagnostics, Diagnostic{
+			Range:    pointRange(toPosition(err.Pos)),
+			Severity: SEVERITY_ERROR,
+			Source:   "monkey",
+			Message:  err.Msg,
+		})
+	}
+	if len(doc.errors) == 0 {
+		for _, issue := range (&lint.Config{}).Check(&doc.program, doc.comments) {
+			diagnostics = append(diagnostics, Diagnostic{
+				Range:    pointRange(toPosition(issue.Pos)),
+				Severity: SEVERITY_WARNING,
+				Code:     issue.Rule,
+				Source:   "monkey lint",
+				Message:  issue.Message,
+			})
+		}
+	}
+	return s.publish(uri, diagnostics)
+}
+
+func (s *Server) publish(uri string, diagnostics []Diagnostic) error {
+	return s.conn.write(notification{
+		JSONRPC: "2.0",
+		Method:  "textDocument/publishDiagnostics",
+		Params:  publishDiagnosticsParams{URI: uri, Diagnostics: diagnostics},
+	})
+}
+
+func pointRange(pos Position) Range {
+	end := pos
+	end.Character++
+	return Range{Start: pos, End: end}
+}
+
+// lookup decodes position params and finds the identifier there.
+func (s *Server) lookup(params json.RawMessage) (*document, *ast.Identifier, error) {
+	var p positionParams
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, nil, err
+	}
+	doc, ok := s.docs[p.TextDocument.URI]
+	if !ok {
+		return nil, nil, nil
+	}
+	return doc, doc.identAt(p.Position.token()), nil
+}
+
+func (s *Server) hover(params json.RawMessage) (interface{}, error) {
+	doc, ident, err := s.lookup(params)
+	if err != nil || ident == nil {
+		return nil, err
+	}
+	text := ""
+	if def, ok := doc.refs[ident]; ok {
+		text = doc.describe(def)
+	} else if evaluator.IsBuiltin(ident.Value) {
+		text = "builtin function " + ident.Value
+	} else if detail := describePrelude(ident.Value); detail != "" {
+		text = detail
+	} else {
+		return nil, nil
+	}
+	return Hover{
+		Contents: MarkupContent{Kind: "markdown", Value: "```monkey\n" + text + "\n```"},
+		Range:    identRange(ident),
+	}, nil
+}
+
+func (s *Server) definition(params json.RawMessage) (interface{}, error) {
+	doc, ident, err := s.lookup(params)
+	if err != nil || ident == nil {
+		return nil, err
+	}
+	def, ok := doc.refs[ident]
+	if !ok {
+		return nil, nil
+	}
+	return Location{URI: doc.uri, Range: identRange(def.name)}, nil
+}
+
+func (s *Server) completion(params json.RawMessage) (interface{}, error) {
+	var p positionParams
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, err
+	}
+	items := []CompletionItem{}
+	doc, ok := s.docs[p.TextDocument.URI]
+	if !ok {
+		return items, nil
+	}
+	seen := make(map[string]bool)
+	for _, def := range doc.visible(p.Position.token()) {
+		seen[def.name.Value] = true
+		kind := COMPLETION_VARIABLE
+		if _, ok := def.value.(*ast.FunctionLiteral); ok {
+			kind = COMPLETION_FUNCTION
+		}
+		items = append(items, CompletionItem{Label: def.name.Value, Kind: kind, Detail: doc.describe(def)})
+	}
+	for _, name := range prelude.Env().Names() {
+		if !seen[name] {
+			seen[name] = true
+			kind := COMPLETION_VARIABLE
+			if value, _ := prelude.Env().Get(name); value.Type() == object.FUNCTION_OBJ {
+				kind = COMPLETION_FUNCTION
+			}
+			items = append(items, CompletionItem{Label: name, Kind: kind, Detail: describePrelude(name)})
+		}
+	}
+	for _, name := range evaluator.BuiltinNames() {
+		if !seen[name] {
+			items = append(items, CompletionItem{Label: name, Kind: COMPLETION_FUNCTION, Detail: "builtin function"})
+		}
+	}
+	return items, nil
+}
+
+func (s *Server) documentSymbol(params json.RawMessage) (interface{}, error) {
+	var p documentParams
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, err
+	}
+	doc, ok := s.docs[p.TextDocument.URI]
+	if !ok {
+		return []DocumentSymbol{}, nil
+	}
+	return symbols(doc.program.Statements), nil
+}
+
+// symbols lists the let statements in stmts, with the bindings local to
+// a function as its children.
+func symbols(stmts []ast.Statement) []DocumentSymbol {
+	syms := []DocumentSymbol{}
+	for _, stmt := range stmts {
+		let, ok := stmt.(*ast.LetStatement)
+		if !ok {
+			continue
+		}
+		sym := DocumentSymbol{
+			Name:           let.Name.Value,
+			Kind:           SYMBOL_VARIABLE,
+			Range:          Range{Start: toPosition(let.Token.Position()), End: identRange(let.Name).End},
+			SelectionRange: identRange(let.Name),
+		}
+		if fn, ok := let.Value.(*ast.FunctionLiteral); ok {
+			sym.Kind = SYMBOL_FUNCTION
+			sym.Detail = signature(fn)
+			sym.Range.End = pointRange(toPosition(fn.Body.Rbrace.Position())).End
+			sym.Children = symbols(fn.Body.Statements)
+		}
+		syms = append(syms, sym)
+	}
+	return syms
+}
+
+func (s *Server) formatting(params json.RawMessage) (interface{}, error) {
+	var p documentParams
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, err
+	}
+	doc, ok := s.docs[p.TextDocument.URI]
+	if !ok {
+		return nil, nil
+	}
+	formatted, err := format.Source([]byte(doc.text))
+	if err != nil {
+		// Nothing to offer until the syntax errors are fixed.
+		return nil, nil
+	}
+	lines := strings.Split(doc.text, "\n")
+	end := Position{Line: len(lines) - 1, Character: len(lines[len(lines)-1])}
+	return []TextEdit{{Range: Range{End: end}, NewText: string(formatted)}}, nil
+}
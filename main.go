@@ -1,22 +1,21 @@
 package main
 
-import (
-	"fmt"
-	"os"
-
-	"github.com/shozawa/monkey/interpreter"
-	"github.com/shozawa/monkey/repl"
-)
+import "os"
 
 func main() {
 	if len(os.Args) > 1 {
-		file, err := os.Open(os.Args[1])
-		if err != nil {
-			fmt.Printf("can't open file: %q\n", os.Args[1])
+		switch os.Args[1] {
+		case "fmt":
+			os.Exit(runFmt(os.Args[2:]))
+		case "lint":
+			os.Exit(runLint(os.Args[2:]))
+		case "lsp":
+			os.Exit(runLsp(os.Args[2:]))
+		case "debug":
+			os.Exit(runDebug(os.Args[2:]))
+		case "test":
+			os.Exit(runTest(os.Args[2:]))
 		}
-		defer file.Close()
-		interpreter.Execute(file)
-	} else {
-		repl.Start(os.Stdin, os.Stdout)
 	}
+	os.Exit(runScript(os.Args[1:]))
 }
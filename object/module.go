@@ -0,0 +1,117 @@
+package object
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// File identifies the source file an environment was evaluated from,
+// together with the file that imported it.
+type File struct {
+	Path         string
+	ImportedFrom *File
+}
+
+// Imports reports whether path is this file or one of its importers.
+func (f *File) Imports(path string) bool {
+	for ; f != nil; f = f.ImportedFrom {
+		if f.Path == path {
+			return true
+		}
+	}
+	return false
+}
+
+// Chain lists the import chain ending at this file, outermost first.
+func (f *File) Chain() []string {
+	var chain []string
+	for ; f != nil; f = f.ImportedFrom {
+		chain = append([]string{f.Path}, chain...)
+	}
+	return chain
+}
+
+type Module struct {
+	Path string
+	Env  *Environment
+}
+
+func (m *Module) Type() ObjectType { return MODULE_OBJ }
+func (m *Module) Inspect() string  { return fmt.Sprintf("module(%q)", m.Path) }
+
+// ModuleCache makes sure every module of a runtime is loaded only once,
+// even when several tasks import it at the same time.
+type ModuleCache struct {
+	mu      sync.Mutex
+	entries map[string]*moduleEntry
+	// waiting maps each task blocked in Load, identified by its runtime,
+	// to the entry it waits for.
+	waiting map[*Runtime]*moduleEntry
+}
+
+type moduleEntry struct {
+	path   string
+	owner  *Runtime
+	done   chan struct{}
+	result Object
+}
+
+// Load returns the cached module for path, calling load to evaluate it
+// on first use. The task loading it is identified by rt. Errors are
+// handed to every waiting caller but are not cached, so a later import
+// retries. When waiting for another task would close a loop of tasks
+// waiting on each other's modules, Load returns an import cycle error
+// instead.
+func (c *ModuleCache) Load(rt *Runtime, path string, load func() Object) Object {
+	c.mu.Lock()
+	if c.entries == nil {
+		c.entries = make(map[string]*moduleEntry)
+		c.waiting = make(map[*Runtime]*moduleEntry)
+	}
+	if entry, ok := c.entries[path]; ok {
+		select {
+		case <-entry.done:
+			c.mu.Unlock()
+			return entry.result
+		default:
+		}
+		if chain := c.cycle(rt, entry); chain != nil {
+			c.mu.Unlock()
+			return &Error{Message: "import cycle: " + strings.Join(chain, " -> ")}
+		}
+		c.waiting[rt] = entry
+		c.mu.Unlock()
+		<-entry.done
+		c.mu.Lock()
+		delete(c.waiting, rt)
+		c.mu.Unlock()
+		return entry.result
+	}
+	entry := &moduleEntry{path: path, owner: rt, done: make(chan struct{})}
+	c.entries[path] = entry
+	c.mu.Unlock()
+
+	entry.result = load()
+	if _, ok := entry.result.(*Module); !ok {
+		c.mu.Lock()
+		delete(c.entries, path)
+		c.mu.Unlock()
+	}
+	close(entry.done)
+	return entry.result
+}
+
+// cycle follows the tasks that entry's owner waits on, and returns the
+// paths of the modules involved if they lead back to rt. It must be
+// called with c.mu held.
+func (c *ModuleCache) cycle(rt *Runtime, entry *moduleEntry) []string {
+	var chain []string
+	for e := entry; e != nil; e = c.waiting[e.owner] {
+		chain = append(chain, e.path)
+		if e.owner == rt {
+			return append([]string{e.path}, chain...)
+		}
+	}
+	return nil
+}
@@ -1,13 +1,22 @@
 package object
 
 import (
+	"errors"
 	"fmt"
+	"hash/fnv"
+	"math/big"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/shozawa/monkey/ast"
 )
 
 type ObjectType string
-type BuiltinFunction func(args ...Object) Object
+type BuiltinFunction func(env *Environment, args ...Object) Object
 
 const (
 	INTEGER_OBJ      = "INTEGER"
@@ -18,34 +27,159 @@ const (
 	ERROR_OBJ        = "ERROR"
 	RETURN_VALUE_OBJ = "RETURN_VALUE"
 	BUILTIN_OBJ      = "BUILTIN"
+	TASK_OBJ         = "TASK"
+	CHANNEL_OBJ      = "CHANNEL"
+	MODULE_OBJ       = "MODULE"
+	ARRAY_OBJ        = "ARRAY"
+	HASH_OBJ         = "HASH"
+	REGEX_OBJ        = "REGEX"
+	TIME_OBJ         = "TIME"
+	BIG_INTEGER_OBJ  = "BIG_INTEGER"
 )
 
 func NewEnclosedEnvironment(outer *Environment) *Environment {
 	env := NewEnv()
 	env.outer = outer
+	env.runtime = outer.runtime
 	return env
 }
 
+// Environment is safe for concurrent use. A frozen environment is
+// read-only and can be shared between evaluations running in parallel;
+// each of them encloses it in a child of its own, so bindings made
+// later land in the child and shadow the shared ones instead of
+// mutating them. Freezing may happen at any time, even while other
+// goroutines use the environment.
 type Environment struct {
-	store map[string]Object
-	outer *Environment
+	mu      sync.RWMutex
+	store   map[string]Object
+	outer   *Environment
+	runtime *Runtime
+	file    *File
+	frozen  atomic.Bool
 }
 
-func (e *Environment) Set(k string, v Object) {
+// ErrFrozen is returned when binding a name in a frozen environment.
+var ErrFrozen = errors.New("environment is frozen")
+
+// Set binds k to v, or returns ErrFrozen if the environment is frozen.
+func (e *Environment) Set(k string, v Object) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.frozen.Load() {
+		return ErrFrozen
+	}
 	e.store[k] = v
+	return nil
 }
 
 func (e *Environment) Get(k string) (Object, bool) {
-	obj, ok := e.store[k]
+	obj, ok := e.lookup(k)
 	if !ok && e.outer != nil {
 		obj, ok = e.outer.Get(k)
 	}
 	return obj, ok
 }
 
+// GetLocal looks k up in this environment only, ignoring enclosing ones.
+func (e *Environment) GetLocal(k string) (Object, bool) {
+	return e.lookup(k)
+}
+
+func (e *Environment) lookup(k string) (Object, bool) {
+	if e.frozen.Load() {
+		// Set refuses to write once frozen is seen, so the store no
+		// longer changes.
+		obj, ok := e.store[k]
+		return obj, ok
+	}
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	obj, ok := e.store[k]
+	return obj, ok
+}
+
+// Names returns every name visible from this environment, sorted.
+func (e *Environment) Names() []string {
+	seen := make(map[string]bool)
+	for env := e; env != nil; env = env.outer {
+		env.mu.RLock()
+		for k := range env.store {
+			seen[k] = true
+		}
+		env.mu.RUnlock()
+	}
+	names := make([]string, 0, len(seen))
+	for k := range seen {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LocalNames returns the sorted names bound in e itself, not in the
+// environments enclosing it.
+func (e *Environment) LocalNames() []string {
+	e.mu.RLock()
+	names := make([]string, 0, len(e.store))
+	for k := range e.store {
+		names = append(names, k)
+	}
+	e.mu.RUnlock()
+	sort.Strings(names)
+	return names
+}
+
+// Outer returns the enclosing environment, nil for a root environment.
+func (e *Environment) Outer() *Environment {
+	return e.outer
+}
+
+// Freeze makes the environment read-only. Set calls made after it
+// returns fail with ErrFrozen.
+func (e *Environment) Freeze() {
+	e.mu.Lock()
+	e.frozen.Store(true)
+	e.mu.Unlock()
+}
+
+func (e *Environment) Frozen() bool {
+	return e.frozen.Load()
+}
+
+// File returns the source file the environment belongs to, or nil for
+// code that was not read from a file.
+func (e *Environment) File() *File {
+	for env := e; env != nil; env = env.outer {
+		if env.file != nil {
+			return env.file
+		}
+	}
+	return nil
+}
+
+func (e *Environment) SetFile(f *File) {
+	e.file = f
+}
+
+func (e *Environment) Runtime() *Runtime {
+	return e.runtime
+}
+
+func (e *Environment) SetRuntime(rt *Runtime) {
+	e.runtime = rt
+}
+
+// NewEnv returns an environment whose runtime grants no capabilities.
 func NewEnv() *Environment {
+	return NewEnvWithRuntime(&Runtime{})
+}
+
+// NewEnvWithRuntime returns a top-level environment for rt, enclosed by
+// rt's prelude if it has one.
+func NewEnvWithRuntime(rt *Runtime) *Environment {
 	store := make(map[string]Object)
-	return &Environment{store: store}
+	return &Environment{store: store, outer: rt.Prelude, runtime: rt}
 }
 
 type Object interface {
@@ -60,12 +194,22 @@ type Integer struct {
 func (i *Integer) Type() ObjectType { return INTEGER_OBJ }
 func (i *Integer) Inspect() string  { return fmt.Sprintf("%d", i.Value) }
 
+// BigInteger holds the integers that do not fit in an Integer. Values
+// that do are always Integers, so the two types never hold the same
+// number.
+type BigInteger struct {
+	Value *big.Int
+}
+
+func (b *BigInteger) Type() ObjectType { return BIG_INTEGER_OBJ }
+func (b *BigInteger) Inspect() string  { return b.Value.String() }
+
 type String struct {
 	Value string
 }
 
 func (s *String) Type() ObjectType { return STRING_OBJ }
-func (s *String) Inspect() string  { return s.Value }
+func (s *String) Inspect() string  { return ast.Quote(s.Value) }
 
 type Bool struct {
 	Value bool
@@ -75,13 +219,135 @@ func (b *Bool) Type() ObjectType { return BOOL_OBJ }
 func (b *Bool) Inspect() string  { return fmt.Sprintf("%v", b.Value) }
 
 type Function struct {
+	// Name is the let binding the function literal was evaluated for,
+	// empty for anonymous functions.
+	Name       string
 	Parameters []*ast.Identifier
 	Body       *ast.BlockStatement
 	Env        *Environment
 }
 
 func (f *Function) Type() ObjectType { return FUNCTION_OBJ }
-func (f *Function) Inspect() string  { return "TODO" }
+func (f *Function) Inspect() string {
+	params := make([]string, len(f.Parameters))
+	for i, p := range f.Parameters {
+		params[i] = p.Value
+	}
+	return fmt.Sprintf("fn(%s) { ... }", strings.Join(params, ", "))
+}
+
+type Regex struct {
+	Value *regexp.Regexp
+}
+
+func (r *Regex) Type() ObjectType { return REGEX_OBJ }
+func (r *Regex) Inspect() string  { return ast.QuoteRegex(r.Value.String()) }
+
+type Time struct {
+	Value time.Time
+}
+
+func (t *Time) Type() ObjectType { return TIME_OBJ }
+func (t *Time) Inspect() string  { return t.Value.Format(time.RFC3339Nano) }
+
+type Array struct {
+	Elements []Object
+}
+
+func (a *Array) Type() ObjectType { return ARRAY_OBJ }
+func (a *Array) Inspect() string {
+	elements := make([]string, len(a.Elements))
+	for i, e := range a.Elements {
+		elements[i] = e.Inspect()
+	}
+	return fmt.Sprintf("[%s]", strings.Join(elements, ", "))
+}
+
+// HashKey identifies a value used as a key of a Hash.
+type HashKey struct {
+	Type  ObjectType
+	Value uint64
+}
+
+// Hashable is implemented by the values that can be hash keys.
+type Hashable interface {
+	Object
+	HashKey() HashKey
+}
+
+func (i *Integer) HashKey() HashKey {
+	return HashKey{Type: i.Type(), Value: uint64(i.Value)}
+}
+
+func (b *BigInteger) HashKey() HashKey {
+	h := fnv.New64a()
+	h.Write([]byte(b.Value.String()))
+	return HashKey{Type: b.Type(), Value: h.Sum64()}
+}
+
+func (b *Bool) HashKey() HashKey {
+	if b.Value {
+		return HashKey{Type: b.Type(), Value: 1}
+	}
+	return HashKey{Type: b.Type(), Value: 0}
+}
+
+func (s *String) HashKey() HashKey {
+	h := fnv.New64a()
+	h.Write([]byte(s.Value))
+	return HashKey{Type: s.Type(), Value: h.Sum64()}
+}
+
+type HashPair struct {
+	Key   Hashable
+	Value Object
+}
+
+// Hash maps keys to values, remembering the order keys were first set
+// in so it prints and iterates deterministically.
+type Hash struct {
+	pairs map[HashKey]HashPair
+	keys  []HashKey
+}
+
+func NewHash() *Hash {
+	return &Hash{pairs: make(map[HashKey]HashPair)}
+}
+
+func (h *Hash) Set(key Hashable, value Object) {
+	k := key.HashKey()
+	if _, ok := h.pairs[k]; !ok {
+		h.keys = append(h.keys, k)
+	}
+	h.pairs[k] = HashPair{Key: key, Value: value}
+}
+
+func (h *Hash) Get(key Hashable) (Object, bool) {
+	pair, ok := h.pairs[key.HashKey()]
+	return pair.Value, ok
+}
+
+func (h *Hash) Len() int {
+	return len(h.keys)
+}
+
+// Pairs returns the pairs of the hash in insertion order.
+func (h *Hash) Pairs() []HashPair {
+	pairs := make([]HashPair, len(h.keys))
+	for i, k := range h.keys {
+		pairs[i] = h.pairs[k]
+	}
+	return pairs
+}
+
+func (h *Hash) Type() ObjectType { return HASH_OBJ }
+func (h *Hash) Inspect() string {
+	pairs := make([]string, len(h.keys))
+	for i, pair := range h.Pairs() {
+		pairs[i] = pair.Key.Inspect() + ": " + pair.Value.Inspect()
+	}
+	return fmt.Sprintf("{%s}", strings.Join(pairs, ", "))
+}
 
 type Null struct{}
 
@@ -97,14 +363,52 @@ func (r *ReturnValue) Inspect() string  { return r.Value.Inspect() }
 
 type Error struct {
 	Message string
+	// Exit marks the error raised by exit to unwind the evaluation,
+	// which the host turns into the exit status Code.
+	Exit bool
+	Code int
 }
 
 func (e *Error) Type() ObjectType { return ERROR_OBJ }
 func (e *Error) Inspect() string  { return "ERROR: " + e.Message }
 
 type Builtin struct {
-	Fn BuiltinFunction
+	Fn           BuiltinFunction
+	Capabilities Capability
 }
 
 func (b *Builtin) Type() ObjectType { return BUILTIN_OBJ }
 func (b *Builtin) Inspect() string  { return "builtin function" }
+
+// Task is the handle of a function running in its own goroutine.
+type Task struct {
+	done   chan struct{}
+	result Object
+}
+
+func NewTask() *Task {
+	return &Task{done: make(chan struct{})}
+}
+
+// Resolve records the task's result and wakes every waiter. It must be
+// called exactly once.
+func (t *Task) Resolve(result Object) {
+	t.result = result
+	close(t.done)
+}
+
+// Wait blocks until the task has been resolved and returns its result.
+func (t *Task) Wait() Object {
+	<-t.done
+	return t.result
+}
+
+func (t *Task) Type() ObjectType { return TASK_OBJ }
+func (t *Task) Inspect() string  { return "task" }
+
+type Channel struct {
+	Ch chan Object
+}
+
+func (c *Channel) Type() ObjectType { return CHANNEL_OBJ }
+func (c *Channel) Inspect() string  { return fmt.Sprintf("channel(%d)", cap(c.Ch)) }
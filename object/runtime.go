@@ -0,0 +1,193 @@
+package object
+
+import (
+	"bufio"
+	"io"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shozawa/monkey/ast"
+)
+
+// Capability is a set of privileges a builtin needs from the host.
+type Capability uint
+
+const (
+	CAP_STDOUT Capability = 1 << iota
+	CAP_FS_READ
+	CAP_FS_WRITE
+	CAP_ENV
+	CAP_CLOCK
+	CAP_RANDOM
+	CAP_STDIN
+
+	CAP_NONE Capability = 0
+	CAP_ALL             = CAP_STDOUT | CAP_FS_READ | CAP_FS_WRITE | CAP_ENV | CAP_CLOCK | CAP_RANDOM | CAP_STDIN
+)
+
+var capabilityNames = []struct {
+	cap  Capability
+	name string
+}{
+	{CAP_STDOUT, "stdout"},
+	{CAP_FS_READ, "fs-read"},
+	{CAP_FS_WRITE, "fs-write"},
+	{CAP_ENV, "env"},
+	{CAP_CLOCK, "clock"},
+	{CAP_RANDOM, "random"},
+	{CAP_STDIN, "stdin"},
+}
+
+func (c Capability) String() string {
+	var names []string
+	for _, n := range capabilityNames {
+		if c&n.cap != 0 {
+			names = append(names, n.name)
+		}
+	}
+	if len(names) == 0 {
+		return "none"
+	}
+	return strings.Join(names, "|")
+}
+
+// Runtime holds the host configuration shared by every environment
+// that takes part in one evaluation.
+type Runtime struct {
+	Capabilities Capability
+	Stdin        io.Reader
+	Stdout       io.Writer
+	Stderr       io.Writer
+	// Clock, when set, replaces the system clock, so that hosts such as
+	// tests can freeze time.
+	Clock func() time.Time
+	// Prelude, when set, encloses the top-level environment of the
+	// program and of each module it imports. It must be frozen.
+	Prelude *Environment
+	// Tracer, when set, is told about each step of the evaluation.
+	Tracer Tracer
+
+	parent    *Runtime
+	modules   ModuleCache
+	stdinOnce sync.Once
+	stdin     *bufio.Reader
+	randMu    sync.Mutex
+	rand      *rand.Rand
+}
+
+// Modules returns the module cache, which is shared with the runtimes
+// of spawned tasks.
+func (r *Runtime) Modules() *ModuleCache {
+	for r.parent != nil {
+		r = r.parent
+	}
+	return &r.modules
+}
+
+// Spawned returns the runtime for a task spawned under r. It has r's
+// configuration, and the tracer r's tracer hands out for the task.
+func (r *Runtime) Spawned() *Runtime {
+	child := &Runtime{
+		Capabilities: r.Capabilities,
+		Stdin:        r.Stdin,
+		Stdout:       r.Stdout,
+		Stderr:       r.Stderr,
+		Clock:        r.Clock,
+		Prelude:      r.Prelude,
+		parent:       r,
+	}
+	if r.Tracer != nil {
+		child.Tracer = r.Tracer.Spawn()
+	}
+	return child
+}
+
+// In returns the reader for program input, buffered once for the
+// runtime and its spawned tasks so no line is lost between them. It is
+// empty when the host did not configure one.
+func (r *Runtime) In() *bufio.Reader {
+	for r.parent != nil {
+		r = r.parent
+	}
+	r.stdinOnce.Do(func() {
+		in := r.Stdin
+		if in == nil {
+			in = strings.NewReader("")
+		}
+		r.stdin = bufio.NewReader(in)
+	})
+	return r.stdin
+}
+
+// Seed restarts the random numbers of the runtime and its spawned tasks
+// at the sequence for seed, so that a run can be repeated.
+func (r *Runtime) Seed(seed int64) {
+	for r.parent != nil {
+		r = r.parent
+	}
+	r.randMu.Lock()
+	r.rand = rand.New(rand.NewSource(seed))
+	r.randMu.Unlock()
+}
+
+// Int63n returns a random number in [0, n). Unless Seed was called, the
+// sequence is seeded from the time the first number is drawn.
+func (r *Runtime) Int63n(n int64) int64 {
+	for r.parent != nil {
+		r = r.parent
+	}
+	r.randMu.Lock()
+	defer r.randMu.Unlock()
+	if r.rand == nil {
+		r.rand = rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+	return r.rand.Int63n(n)
+}
+
+// Out returns the writer for program output, discarding it when the
+// host did not configure one.
+func (r *Runtime) Out() io.Writer {
+	if r.Stdout == nil {
+		return io.Discard
+	}
+	return r.Stdout
+}
+
+// Now returns the current time according to the runtime's clock.
+func (r *Runtime) Now() time.Time {
+	if r.Clock == nil {
+		return time.Now()
+	}
+	return r.Clock()
+}
+
+func (r *Runtime) Err() io.Writer {
+	if r.Stderr == nil {
+		return io.Discard
+	}
+	return r.Stderr
+}
+
+func (r *Runtime) Granted(c Capability) bool {
+	return r.Capabilities&c == c
+}
+
+// Tracer observes an evaluation, for debuggers, profilers and coverage
+// tools. Its methods run on the goroutine doing the evaluation, which
+// waits for them to return.
+type Tracer interface {
+	// Statement is called before each statement is evaluated.
+	Statement(stmt ast.Statement, env *Environment)
+	// Call and Return bracket each call of a Monkey function. env is
+	// the environment the function body runs in.
+	Call(fn *Function, env *Environment)
+	Return(fn *Function, result Object)
+	// Branch is called when an if expression has chosen to run its
+	// Consequence, or else its Alternative, which may be absent.
+	Branch(ie *ast.IfExpression, consequence bool, env *Environment)
+	// Spawn returns the tracer for a task started by the traced code,
+	// or nil to leave the task untraced.
+	Spawn() Tracer
+}
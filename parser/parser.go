@@ -1,7 +1,10 @@
 package parser
 
 import (
+	"errors"
 	"fmt"
+	"math/big"
+	"regexp"
 	"strconv"
 
 	"github.com/shozawa/monkey/ast"
@@ -18,6 +21,8 @@ const (
 	PRODUCT     // * or / or %
 	PREFIX      // -x or !x
 	CALL        // myFunction()
+	MEMBER      // module.name
+	INDEX       // array[index]
 )
 
 var precedences = map[token.TokenType]int{
@@ -31,6 +36,8 @@ var precedences = map[token.TokenType]int{
 	token.LT:       LESSGREATER,
 	token.GT:       LESSGREATER,
 	token.LPAREN:   CALL,
+	token.DOT:      MEMBER,
+	token.LBRACKET: INDEX,
 }
 
 type (
@@ -42,7 +49,7 @@ type Parser struct {
 	l         *lexer.Lexer
 	curToken  token.Token
 	peekToken token.Token
-	errors    []string
+	errors    []Error
 
 	prefixParseFns map[token.TokenType]prefixParseFn
 	infixParseFns  map[token.TokenType]infixParseFn
@@ -57,6 +64,7 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerPrefix(token.IDENT, p.parseIdentifier)
 	p.registerPrefix(token.INT, p.parserIntegerLiteral)
 	p.registerPrefix(token.STRING, p.parserStringLiteral)
+	p.registerPrefix(token.REGEX, p.parseRegexLiteral)
 	p.registerPrefix(token.IF, p.parseIfExpression)
 	p.registerPrefix(token.FUNCTION, p.parseFunctionLiteral)
 	p.registerPrefix(token.LPAREN, p.parseGroupedExpression)
@@ -64,6 +72,8 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerPrefix(token.FALSE, p.parseBoolLiteral)
 	p.registerPrefix(token.BANG, p.parserPrefixExpression)
 	p.registerPrefix(token.MINUS, p.parserPrefixExpression)
+	p.registerPrefix(token.LBRACKET, p.parseArrayLiteral)
+	p.registerPrefix(token.LBRACE, p.parseHashLiteral)
 
 	p.infixParseFns = make(map[token.TokenType]infixParseFn)
 	p.registerInfix(token.EQ, p.parseInfixExpression)
@@ -76,6 +86,8 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerInfix(token.LT, p.parseInfixExpression)
 	p.registerInfix(token.GT, p.parseInfixExpression)
 	p.registerInfix(token.LPAREN, p.parseCallExpression)
+	p.registerInfix(token.DOT, p.parseMemberExpression)
+	p.registerInfix(token.LBRACKET, p.parseIndexExpression)
 
 	return p
 }
@@ -90,24 +102,53 @@ func (p *Parser) Parse() (prog ast.Program) {
 	return
 }
 
+// Error is a syntax error at a position in the source.
+type Error struct {
+	Pos token.Position
+	Msg string
+}
+
+func (e Error) String() string {
+	return fmt.Sprintf("%s: %s", e.Pos, e.Msg)
+}
+
+// Errors returns the syntax errors as "line:column: message" strings.
 func (p *Parser) Errors() []string {
+	msgs := make([]string, len(p.errors))
+	for i, err := range p.errors {
+		msgs[i] = err.String()
+	}
+	return msgs
+}
+
+// ErrorList returns the syntax errors with their positions.
+func (p *Parser) ErrorList() []Error {
 	return p.errors
 }
 
 func (p *Parser) peekError(t token.TokenType) {
 	msg := fmt.Sprintf("expected next token to be %s, got %s instead", t, p.peekToken.Type)
-	p.errors = append(p.errors, msg)
+	p.addError(p.peekToken, msg)
 }
 
 func (p *Parser) noPrefixParseFnError(t token.Token) {
-	msg := fmt.Sprintf("no prefix parse function for %s found", t)
-	p.errors = append(p.errors, msg)
+	msg := fmt.Sprintf("no prefix parse function for %s %q found", t.Type, t.Literal)
+	p.addError(t, msg)
+}
+
+// addError records msg at the position of tok.
+func (p *Parser) addError(tok token.Token, msg string) {
+	p.errors = append(p.errors, Error{Pos: tok.Position(), Msg: msg})
 }
 
 func (p *Parser) parseStatement() ast.Statement {
 	switch p.curToken.Type {
 	case token.LET:
-		return p.parseLetStatement()
+		// Return an untyped nil so Parse drops a malformed let.
+		if stmt := p.parseLetStatement(); stmt != nil {
+			return stmt
+		}
+		return nil
 	case token.RETURN:
 		return p.parseReturnStatement()
 	default:
@@ -166,6 +207,12 @@ func (p *Parser) parseLetStatement() *ast.LetStatement {
 
 func (p *Parser) parseReturnStatement() *ast.ReturnStatement {
 	stmt := &ast.ReturnStatement{Token: p.curToken}
+	if p.peekTokenIs(token.SEMICOLON) || p.peekTokenIs(token.RBRACE) || p.peekTokenIs(token.EOF) {
+		if p.peekTokenIs(token.SEMICOLON) {
+			p.nextToken()
+		}
+		return stmt
+	}
 	p.nextToken() // consume 'return'
 	stmt.ReturnValue = p.parseExpression(LOWEST)
 	if p.peekTokenIs(token.SEMICOLON) {
@@ -191,6 +238,10 @@ func (p *Parser) parserFunctionParameters() []*ast.Identifier {
 
 	p.nextToken() // consume '('
 
+	if p.curTokenIs(token.RPAREN) {
+		return params
+	}
+
 	ident, ok := p.parseIdentifier().(*ast.Identifier)
 	if !ok {
 		return nil
@@ -217,14 +268,68 @@ func (p *Parser) parserFunctionParameters() []*ast.Identifier {
 
 func (p *Parser) parseCallExpression(function ast.Expression) ast.Expression {
 	exp := &ast.CallExpression{Token: p.curToken, Function: function}
-	exp.Arguments = p.parseCallArguments()
+	exp.Arguments = p.parseExpressionList(token.RPAREN)
+	exp.Rparen = p.curToken
+	return exp
+}
+
+func (p *Parser) parseArrayLiteral() ast.Expression {
+	array := &ast.ArrayLiteral{Token: p.curToken}
+	array.Elements = p.parseExpressionList(token.RBRACKET)
+	array.Rbracket = p.curToken
+	return array
+}
+
+func (p *Parser) parseHashLiteral() ast.Expression {
+	hash := &ast.HashLiteral{Token: p.curToken}
+	for !p.peekTokenIs(token.RBRACE) {
+		p.nextToken()
+		key := p.parseExpression(LOWEST)
+		if !p.expectPeek(token.COLON) {
+			return nil
+		}
+		p.nextToken() // consume ':'
+		value := p.parseExpression(LOWEST)
+		hash.Pairs = append(hash.Pairs, ast.HashPair{Key: key, Value: value})
+		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+	p.nextToken() // consume last token before '}'
+	hash.Rbrace = p.curToken
+	return hash
+}
+
+func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
+	exp := &ast.IndexExpression{Token: p.curToken, Left: left}
+	p.nextToken() // consume '['
+	exp.Index = p.parseExpression(LOWEST)
+	if !p.expectPeek(token.RBRACKET) {
+		return nil
+	}
+	return exp
+}
+
+func (p *Parser) parseMemberExpression(object ast.Expression) ast.Expression {
+	exp := &ast.MemberExpression{Token: p.curToken, Object: object}
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+	exp.Property = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
 	return exp
 }
 
-func (p *Parser) parseCallArguments() []ast.Expression {
+// parseExpressionList parses comma-separated expressions up to end,
+// the arguments of a call or the elements of an array.
+func (p *Parser) parseExpressionList(end token.TokenType) []ast.Expression {
 	var args []ast.Expression
 
-	p.nextToken() // consume '('
+	if p.peekTokenIs(end) {
+		p.nextToken()
+		return args
+	}
+
+	p.nextToken() // consume '(' or '['
 
 	arg := p.parseExpression(LOWEST)
 	args = append(args, arg)
@@ -238,7 +343,7 @@ func (p *Parser) parseCallArguments() []ast.Expression {
 		args = append(args, arg)
 	}
 
-	p.expectPeek(token.RPAREN)
+	p.expectPeek(end)
 
 	return args
 }
@@ -287,6 +392,10 @@ func (p *Parser) parseBlockStatement() *ast.BlockStatement {
 		}
 		p.nextToken()
 	}
+	block.Rbrace = p.curToken
+	if p.curTokenIs(token.EOF) {
+		p.addError(p.curToken, "expected } to close block, got EOF")
+	}
 
 	return block
 }
@@ -298,9 +407,14 @@ func (p *Parser) parseIdentifier() ast.Expression {
 func (p *Parser) parserIntegerLiteral() ast.Expression {
 	lit := &ast.IntegerLiteral{Token: p.curToken}
 	value, err := strconv.ParseInt(p.curToken.Literal, 0, 64)
+	if errors.Is(err, strconv.ErrRange) {
+		if n, ok := new(big.Int).SetString(p.curToken.Literal, 0); ok {
+			return &ast.BigIntegerLiteral{Token: p.curToken, Value: n}
+		}
+	}
 	if err != nil {
 		msg := fmt.Sprintf("could not parse %q as integer", p.curToken.Literal)
-		p.errors = append(p.errors, msg)
+		p.addError(p.curToken, msg)
 		return nil
 	}
 	lit.Value = value
@@ -311,6 +425,15 @@ func (p *Parser) parserStringLiteral() ast.Expression {
 	return &ast.StringLiteral{Token: p.curToken, Value: p.curToken.Literal}
 }
 
+func (p *Parser) parseRegexLiteral() ast.Expression {
+	re, err := regexp.Compile(p.curToken.Literal)
+	if err != nil {
+		p.addError(p.curToken, fmt.Sprintf("invalid regex %s: %s", ast.QuoteRegex(p.curToken.Literal), err))
+		return nil
+	}
+	return &ast.RegexLiteral{Token: p.curToken, Value: p.curToken.Literal, Regexp: re}
+}
+
 func (p *Parser) parseBoolLiteral() ast.Expression {
 	return &ast.BoolLiteral{Token: p.curToken, Value: p.curToken.Literal}
 }
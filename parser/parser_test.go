@@ -216,6 +216,122 @@ func TestCallExpression(t *testing.T) {
 	testInfixExpression(t, call.Arguments[2], 4, "*", 5)
 }
 
+func TestEmptyParameterAndArgumentLists(t *testing.T) {
+	program := testParse(t, "let f = fn() { 1 }; f();")
+	if got := len(program.Statements); got != 2 {
+		t.Fatalf("len(program.Statements) not 2. got=%d.\n", got)
+	}
+	let := program.Statements[0].(*ast.LetStatement)
+	fn, ok := let.Value.(*ast.FunctionLiteral)
+	if !ok {
+		t.Fatalf("let.Value not ast.FunctionLiteral. got=%T.\n", let.Value)
+	}
+	if got := len(fn.Parameters); got != 0 {
+		t.Errorf("len(fn.Parameters) not 0. got=%d.\n", got)
+	}
+	stmt := program.Statements[1].(*ast.ExpressionStatement)
+	call, ok := stmt.Expression.(*ast.CallExpression)
+	if !ok {
+		t.Fatalf("stmt.Expression not ast.CallExpression. got=%T.\n", stmt.Expression)
+	}
+	if got := len(call.Arguments); got != 0 {
+		t.Errorf("len(call.Arguments) not 0. got=%d.\n", got)
+	}
+}
+
+func TestMemberExpression(t *testing.T) {
+	program := testParse(t, "m.add(1, 2)")
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	call, ok := stmt.Expression.(*ast.CallExpression)
+	if !ok {
+		t.Fatalf("stmt.Expression not ast.CallExpression. got=%T.\n", stmt.Expression)
+	}
+	member, ok := call.Function.(*ast.MemberExpression)
+	if !ok {
+		t.Fatalf("call.Function not ast.MemberExpression. got=%T.\n", call.Function)
+	}
+	testIdentifier(t, member.Object, "m")
+	testIdentifier(t, member.Property, "add")
+}
+
+func TestArrayLiteral(t *testing.T) {
+	program := testParse(t, "[1, 2 * 2, x]")
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	array, ok := stmt.Expression.(*ast.ArrayLiteral)
+	if !ok {
+		t.Fatalf("stmt.Expression not ast.ArrayLiteral. got=%T.\n", stmt.Expression)
+	}
+	if len(array.Elements) != 3 {
+		t.Fatalf("len(array.Elements) not 3. got=%d.\n", len(array.Elements))
+	}
+	testIntegerLiteral(t, array.Elements[0], 1)
+	testInfixExpression(t, array.Elements[1], 2, "*", 2)
+	testIdentifier(t, array.Elements[2], "x")
+}
+
+func TestHashLiteral(t *testing.T) {
+	program := testParse(t, `{"one": 1, two: 1 + 1, 3: x}`)
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	hash, ok := stmt.Expression.(*ast.HashLiteral)
+	if !ok {
+		t.Fatalf("stmt.Expression not ast.HashLiteral. got=%T.\n", stmt.Expression)
+	}
+	if len(hash.Pairs) != 3 {
+		t.Fatalf("len(hash.Pairs) not 3. got=%d.\n", len(hash.Pairs))
+	}
+	if key, ok := hash.Pairs[0].Key.(*ast.StringLiteral); !ok || key.Value != "one" {
+		t.Errorf("first key not \"one\". got=%s", hash.Pairs[0].Key)
+	}
+	testIntegerLiteral(t, hash.Pairs[0].Value, 1)
+	testIdentifier(t, hash.Pairs[1].Key, "two")
+	testInfixExpression(t, hash.Pairs[1].Value, 1, "+", 1)
+	testIntegerLiteral(t, hash.Pairs[2].Key, 3)
+	testIdentifier(t, hash.Pairs[2].Value, "x")
+
+	for _, input := range []string{"{}", `{"a": 1,}`} {
+		program := testParse(t, input)
+		if hash, ok := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.HashLiteral); !ok || len(hash.Pairs) > 1 {
+			t.Errorf("%s not parsed as a hash literal. got=%s", input, program.String())
+		}
+	}
+}
+
+func TestIndexExpression(t *testing.T) {
+	program := testParse(t, "xs[1 + 1]")
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	index, ok := stmt.Expression.(*ast.IndexExpression)
+	if !ok {
+		t.Fatalf("stmt.Expression not ast.IndexExpression. got=%T.\n", stmt.Expression)
+	}
+	testIdentifier(t, index.Left, "xs")
+	testInfixExpression(t, index.Index, 1, "+", 1)
+}
+
+func TestRegexLiteral(t *testing.T) {
+	program := testParse(t, `/a\/(b+)/`)
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	re, ok := stmt.Expression.(*ast.RegexLiteral)
+	if !ok {
+		t.Fatalf("stmt.Expression not ast.RegexLiteral. got=%T.\n", stmt.Expression)
+	}
+	if re.Value != "a/(b+)" {
+		t.Errorf("re.Value not %q. got=%q", "a/(b+)", re.Value)
+	}
+	if re.String() != `/a\/(b+)/` {
+		t.Errorf("re.String() not %q. got=%q", `/a\/(b+)/`, re.String())
+	}
+	if re.Regexp == nil || re.Regexp.String() != re.Value {
+		t.Errorf("re.Regexp not compiled from %q. got=%v", re.Value, re.Regexp)
+	}
+
+	p := New(lexer.New("let r = /a(b/;"))
+	p.Parse()
+	want := "1:9: invalid regex /a(b/: error parsing regexp: missing closing ): `a(b`"
+	if errors := p.Errors(); len(errors) != 1 || errors[0] != want {
+		t.Errorf("errors not [%q]. got=%q", want, errors)
+	}
+}
+
 func TestParseLetStatement(t *testing.T) {
 	input := `
 	let five = 5;
@@ -235,6 +351,22 @@ func TestParseLetStatement(t *testing.T) {
 	}
 }
 
+func TestMalformedLetStatement(t *testing.T) {
+	p := New(lexer.New("let = 1;"))
+	program := p.Parse()
+	if len(p.ErrorList()) == 0 {
+		t.Fatal("parser reported no errors")
+	}
+	if pos := p.ErrorList()[0].Pos; pos.Line != 1 || pos.Column != 5 {
+		t.Errorf("error position not 1:5. got=%s", pos)
+	}
+	for _, stmt := range program.Statements {
+		if _, ok := stmt.(*ast.LetStatement); ok {
+			t.Errorf("malformed let statement kept in program. got=%#v", stmt)
+		}
+	}
+}
+
 func TestParseReturnStatement(t *testing.T) {
 	input := "return 10;"
 	program := testParse(t, input)
@@ -250,6 +382,20 @@ func TestParseReturnStatement(t *testing.T) {
 	}
 }
 
+func TestParseBareReturnStatement(t *testing.T) {
+	program := testParse(t, "fn() { return; }; fn() { return }")
+	for _, s := range program.Statements {
+		fn := s.(*ast.ExpressionStatement).Expression.(*ast.FunctionLiteral)
+		stmt, ok := fn.Body.Statements[0].(*ast.ReturnStatement)
+		if !ok {
+			t.Fatalf("fn.Body.Statements[0] is not ast.ReturnStatement. got=%T", fn.Body.Statements[0])
+		}
+		if stmt.ReturnValue != nil {
+			t.Errorf("stmt.ReturnValue not nil. got=%s", stmt.ReturnValue)
+		}
+	}
+}
+
 func testLetStatment(t *testing.T, s ast.Statement, name string, value int64) bool {
 	if literal := s.TokenLiteral(); literal != "let" {
 		t.Errorf("s.TokenLiteral not 'let'. got=%q", literal)
@@ -276,6 +422,21 @@ func testLetStatment(t *testing.T, s ast.Statement, name string, value int64) bo
 	return true
 }
 
+func TestBigIntegerLiteral(t *testing.T) {
+	l := lexer.New("9223372036854775808;")
+	p := New(l)
+	program := p.Parse()
+	checkParserErrors(t, p)
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	lit, ok := stmt.Expression.(*ast.BigIntegerLiteral)
+	if !ok {
+		t.Fatalf("exp not *ast.BigIntegerLiteral. got=%T", stmt.Expression)
+	}
+	if got := lit.Value.String(); got != "9223372036854775808" {
+		t.Errorf("lit.Value not 9223372036854775808. got=%s", got)
+	}
+}
+
 func TestIfExpression(t *testing.T) {
 	input := "if (x) { 1 } else { 2 };"
 	l := lexer.New(input)
@@ -322,6 +483,14 @@ func TestOperatorPrecedence(t *testing.T) {
 		{"1 + 2 * 3", "(1 + (2 * 3))"},
 		{"1 + 2 / 3", "(1 + (2 / 3))"},
 		{"(1 + 2) * 3", "((1 + 2) * 3)"},
+		{"add(1, 2 * 3)", "add(1, (2 * 3))"},
+		{"fn(x, y) { return x + y; }", "fn(x, y) { return (x + y); }"},
+		{"if (a < b) { a } else { b }", "if ((a < b)) { a } else { b }"},
+		{`m.f("s")`, `m.f("s")`},
+		{"[]", "[]"},
+		{`{"a": 1 + 2, b: [c]}["a"]`, `({"a": (1 + 2), b: [c]}["a"])`},
+		{"a * [1, 2, 3][b * c] * d", "((a * ([1, 2, 3][(b * c)])) * d)"},
+		{"add(a * b[2], m.xs[1], f(x)[0])", "add((a * (b[2])), (m.xs[1]), (f(x)[0]))"},
 	}
 	for i, test := range tests {
 		l := lexer.New(test.input)
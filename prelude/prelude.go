@@ -0,0 +1,42 @@
+// Package prelude holds the part of the standard library written in
+// Monkey, which hosts put around the programs they run.
+package prelude
+
+import (
+	_ "embed"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/shozawa/monkey/evaluator"
+	"github.com/shozawa/monkey/lexer"
+	"github.com/shozawa/monkey/object"
+	"github.com/shozawa/monkey/parser"
+)
+
+//go:embed prelude.monkey
+var source string
+
+var (
+	once sync.Once
+	env  *object.Environment
+)
+
+// Env returns the frozen environment holding the prelude's bindings,
+// for use as object.Runtime.Prelude. The prelude is evaluated on the
+// first call only; later calls share the result.
+func Env() *object.Environment {
+	once.Do(func() {
+		p := parser.New(lexer.New(source))
+		program := p.Parse()
+		if errs := p.Errors(); len(errs) > 0 {
+			panic("prelude: " + strings.Join(errs, "; "))
+		}
+		env = object.NewEnv()
+		if result := evaluator.Eval(&program, env); result != nil && result.Type() == object.ERROR_OBJ {
+			panic(fmt.Sprintf("prelude: %s", result.Inspect()))
+		}
+		env.Freeze()
+	})
+	return env
+}
@@ -0,0 +1,52 @@
+package prelude
+
+import (
+	"testing"
+
+	"github.com/shozawa/monkey/evaluator"
+	"github.com/shozawa/monkey/lexer"
+	"github.com/shozawa/monkey/object"
+	"github.com/shozawa/monkey/parser"
+)
+
+func TestPrelude(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"identity(3)", "3"},
+		{"compose(fn(x) { x * 2 }, fn(x) { x + 1 })(4)", "10"},
+		{"filter([1, 2, 3, 4], negate(fn(x) { x % 2 == 0 }))", "[1, 3]"},
+		{"sum([1, 2, 3])", "6"},
+		{"sum([])", "0"},
+		{"product([2, 3, 4])", "24"},
+		{"count([1, 5, 7], fn(x) { x > 4 })", "2"},
+		{"find([1, 5, 7], fn(x) { x > 4 })", "5"},
+		{"find([1], fn(x) { x > 4 })", "null"},
+		{"first([1, 2])", "1"},
+		{"last([1, 2])", "2"},
+		{"reverse([1, 2, 3])", "[3, 2, 1]"},
+		{"reverse([])", "[]"},
+		{`enumerate(["a", "b"])`, `[[0, "a"], [1, "b"]]`},
+		{"clamp(12, 0, 10)", "10"},
+		{"let sum = fn(xs) { 0 }; sum([1])", "0"},
+		{"sum([1, true])", "ERROR: type mismatch: INTEGER + BOOLEAN"},
+	}
+	for _, test := range tests {
+		program := parser.New(lexer.New(test.input)).Parse()
+		env := object.NewEnvWithRuntime(&object.Runtime{Prelude: Env()})
+		if got := evaluator.Eval(&program, env).Inspect(); got != test.want {
+			t.Errorf("%s: result not %s. got=%s", test.input, test.want, got)
+		}
+	}
+}
+
+func TestEnvIsCached(t *testing.T) {
+	env := Env()
+	if !env.Frozen() {
+		t.Errorf("prelude environment is not frozen")
+	}
+	if Env() != env {
+		t.Errorf("Env() evaluated the prelude again")
+	}
+}
@@ -0,0 +1,362 @@
+package printer
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"strings"
+
+	"github.com/shozawa/monkey/ast"
+	"github.com/shozawa/monkey/token"
+)
+
+const INDENT = "    "
+
+// Operator precedences, mirroring the parser's.
+const (
+	_ int = iota
+	LOWEST
+	EQUALS
+	LESSGREATER
+	SUM
+	PRODUCT
+	PREFIX
+	CALL
+	MEMBER
+	INDEX
+	PRIMARY
+)
+
+var precedences = map[string]int{
+	"==": EQUALS,
+	"!=": EQUALS,
+	"<":  LESSGREATER,
+	">":  LESSGREATER,
+	"+":  SUM,
+	"-":  SUM,
+	"*":  PRODUCT,
+	"/":  PRODUCT,
+	"%":  PRODUCT,
+}
+
+// Config controls how trivia is printed alongside the syntax tree.
+type Config struct {
+	// Comments, as returned by lexer.Comments, are printed before the
+	// statement, list item or closing brace they precede. A comment that
+	// shared its line with code stays at the end of the printed line.
+	// Array, hash and argument lists holding a comment are printed one
+	// item per line.
+	Comments []token.Token
+	// Source is the text the tree was parsed from. When set, a single
+	// blank line between statements is kept.
+	Source string
+}
+
+// Fprint writes node to w as canonically formatted source.
+func Fprint(w io.Writer, node ast.Node) error {
+	return (&Config{}).Fprint(w, node)
+}
+
+// Sprint returns node as canonically formatted source.
+func Sprint(node ast.Node) string {
+	var buf bytes.Buffer
+	Fprint(&buf, node)
+	return buf.String()
+}
+
+func (c *Config) Fprint(w io.Writer, node ast.Node) error {
+	p := &printer{comments: c.Comments}
+	if c.Source != "" {
+		p.lines = strings.Split(c.Source, "\n")
+	}
+	p.node(node)
+	_, err := w.Write(p.buf.Bytes())
+	return err
+}
+
+type printer struct {
+	buf      bytes.Buffer
+	indent   int
+	comments []token.Token
+	lines    []string
+}
+
+// eof is a position after any source.
+var eof = token.Position{Line: math.MaxInt, Column: math.MaxInt}
+
+func (p *printer) node(node ast.Node) {
+	switch node := node.(type) {
+	case *ast.Program:
+		p.statementList(node.Statements, eof)
+	case ast.Statement:
+		p.statement(node)
+	case ast.Expression:
+		p.expression(node, LOWEST)
+	}
+}
+
+// statementList prints stmts one per line at the current indentation,
+// with the comments that appear before end.
+func (p *printer) statementList(stmts []ast.Statement, end token.Position) {
+	first := true
+	for i, stmt := range stmts {
+		pos := ast.Pos(stmt)
+		p.flushComments(pos, &first)
+		p.lineStart(pos.Line, &first)
+		p.statement(stmt)
+		if endsInBlock(stmt) && i+1 < len(stmts) && continues(stmts[i+1]) {
+			p.buf.WriteString(";")
+		}
+		p.buf.WriteString("\n")
+	}
+	p.flushComments(end, &first)
+}
+
+// lineStart indents a new line for an item from source line, keeping
+// one blank line if the source had any before it.
+func (p *printer) lineStart(line int, first *bool) {
+	if !*first && line >= 2 && line-2 < len(p.lines) && strings.TrimSpace(p.lines[line-2]) == "" {
+		p.buf.WriteString("\n")
+	}
+	*first = false
+	p.buf.WriteString(strings.Repeat(INDENT, p.indent))
+}
+
+func (p *printer) flushComments(before token.Position, first *bool) {
+	for len(p.comments) > 0 && p.comments[0].Position().Before(before) {
+		comment := p.comments[0]
+		p.comments = p.comments[1:]
+		if p.trailing(comment) && bytes.HasSuffix(p.buf.Bytes(), []byte("\n")) {
+			p.buf.Truncate(p.buf.Len() - 1)
+			p.buf.WriteString(" " + comment.Literal + "\n")
+			continue
+		}
+		p.lineStart(comment.Line, first)
+		p.buf.WriteString(comment.Literal + "\n")
+	}
+}
+
+// trailing reports whether code precedes comment on its source line.
+func (p *printer) trailing(comment token.Token) bool {
+	if comment.Line-1 >= len(p.lines) {
+		return false
+	}
+	line := p.lines[comment.Line-1]
+	return comment.Column-1 <= len(line) && strings.TrimSpace(line[:comment.Column-1]) != ""
+}
+
+func (p *printer) hasCommentBefore(pos token.Position) bool {
+	return len(p.comments) > 0 && p.comments[0].Position().Before(pos)
+}
+
+func (p *printer) statement(stmt ast.Statement) {
+	switch stmt := stmt.(type) {
+	case *ast.LetStatement:
+		p.buf.WriteString("let ")
+		p.buf.WriteString(stmt.Name.Value)
+		p.buf.WriteString(" = ")
+		p.expression(stmt.Value, LOWEST)
+		p.buf.WriteString(";")
+	case *ast.ReturnStatement:
+		p.buf.WriteString("return")
+		if stmt.ReturnValue != nil {
+			p.buf.WriteString(" ")
+			p.expression(stmt.ReturnValue, LOWEST)
+		}
+		p.buf.WriteString(";")
+	case *ast.ExpressionStatement:
+		p.expression(stmt.Expression, LOWEST)
+		// Statements ending in a block read better without a semicolon;
+		// statementList adds one where the next statement needs it.
+		if !endsInBlock(stmt) {
+			p.buf.WriteString(";")
+		}
+	case *ast.BlockStatement:
+		p.block(stmt)
+	}
+}
+
+func endsInBlock(stmt ast.Statement) bool {
+	es, ok := stmt.(*ast.ExpressionStatement)
+	if !ok {
+		return false
+	}
+	_, ok = es.Expression.(*ast.IfExpression)
+	return ok
+}
+
+// continues reports whether stmt would be parsed as continuing an if
+// expression printed before it without a semicolon, as -1 does in
+// "if (a) { 1 }\n-1".
+func continues(stmt ast.Statement) bool {
+	es, ok := stmt.(*ast.ExpressionStatement)
+	return ok && es.Expression != nil && startsWithOperator(es.Expression, LOWEST)
+}
+
+// startsWithOperator reports whether exp, printed in context, begins
+// with a token that can also follow an operand: a minus, an opening
+// parenthesis or bracket, or a slash.
+func startsWithOperator(exp ast.Expression, context int) bool {
+	if precedence(exp) < context {
+		return true
+	}
+	switch exp := exp.(type) {
+	case *ast.PrefixExpression:
+		return exp.Operator == "-"
+	case *ast.ArrayLiteral, *ast.RegexLiteral:
+		return true
+	case *ast.Infix:
+		return startsWithOperator(exp.Left, precedences[exp.Operator])
+	case *ast.CallExpression:
+		return startsWithOperator(exp.Function, CALL)
+	case *ast.MemberExpression:
+		return startsWithOperator(exp.Object, CALL)
+	case *ast.IndexExpression:
+		return startsWithOperator(exp.Left, CALL)
+	}
+	return false
+}
+
+func (p *printer) block(block *ast.BlockStatement) {
+	end := block.Rbrace.Position()
+	if len(block.Statements) == 0 && !p.hasCommentBefore(end) {
+		p.buf.WriteString("{}")
+		return
+	}
+	p.buf.WriteString("{\n")
+	p.indent++
+	p.statementList(block.Statements, end)
+	p.indent--
+	p.buf.WriteString(strings.Repeat(INDENT, p.indent))
+	p.buf.WriteString("}")
+}
+
+// expression prints exp, parenthesized when it binds less tightly than
+// the context it appears in.
+func (p *printer) expression(exp ast.Expression, context int) {
+	if precedence(exp) < context {
+		p.buf.WriteString("(")
+		defer p.buf.WriteString(")")
+	}
+	switch exp := exp.(type) {
+	case *ast.Identifier:
+		p.buf.WriteString(exp.Value)
+	case *ast.IntegerLiteral, *ast.BigIntegerLiteral:
+		p.buf.WriteString(exp.TokenLiteral())
+	case *ast.BoolLiteral:
+		p.buf.WriteString(exp.Value)
+	case *ast.StringLiteral:
+		p.buf.WriteString(ast.Quote(exp.Value))
+	case *ast.RegexLiteral:
+		p.buf.WriteString(ast.QuoteRegex(exp.Value))
+	case *ast.PrefixExpression:
+		p.buf.WriteString(exp.Operator)
+		p.expression(exp.Right, PREFIX)
+	case *ast.Infix:
+		prec := precedences[exp.Operator]
+		p.expression(exp.Left, prec)
+		p.buf.WriteString(" " + exp.Operator + " ")
+		// Operators are left-associative, so an equally binding right
+		// operand needs parentheses.
+		p.expression(exp.Right, prec+1)
+	case *ast.IfExpression:
+		p.buf.WriteString("if (")
+		p.expression(exp.Condition, LOWEST)
+		p.buf.WriteString(") ")
+		p.block(exp.Consequence)
+		if exp.Alternative != nil {
+			p.buf.WriteString(" else ")
+			p.block(exp.Alternative)
+		}
+	case *ast.FunctionLiteral:
+		p.buf.WriteString("fn(")
+		for i, param := range exp.Parameters {
+			if i > 0 {
+				p.buf.WriteString(", ")
+			}
+			p.buf.WriteString(param.Value)
+		}
+		p.buf.WriteString(") ")
+		p.block(exp.Body)
+	case *ast.CallExpression:
+		p.expression(exp.Function, CALL)
+		p.list("(", ")", exp.Arguments, exp.Rparen.Position(), func(i int) {
+			p.expression(exp.Arguments[i], LOWEST)
+		})
+	case *ast.MemberExpression:
+		p.expression(exp.Object, CALL)
+		p.buf.WriteString(".")
+		p.buf.WriteString(exp.Property.Value)
+	case *ast.ArrayLiteral:
+		p.list("[", "]", exp.Elements, exp.Rbracket.Position(), func(i int) {
+			p.expression(exp.Elements[i], LOWEST)
+		})
+	case *ast.HashLiteral:
+		keys := make([]ast.Expression, len(exp.Pairs))
+		for i, pair := range exp.Pairs {
+			keys[i] = pair.Key
+		}
+		p.list("{", "}", keys, exp.Rbrace.Position(), func(i int) {
+			p.expression(exp.Pairs[i].Key, LOWEST)
+			p.buf.WriteString(": ")
+			p.expression(exp.Pairs[i].Value, LOWEST)
+		})
+	case *ast.IndexExpression:
+		p.expression(exp.Left, CALL)
+		p.buf.WriteString("[")
+		p.expression(exp.Index, LOWEST)
+		p.buf.WriteString("]")
+	}
+}
+
+// list prints the items starting with starts between open and close,
+// separated by commas. They share a line unless a comment comes before
+// end, which puts each item on a line of its own after the comments
+// that precede it.
+func (p *printer) list(open, close string, starts []ast.Expression, end token.Position, item func(i int)) {
+	p.buf.WriteString(open)
+	if !p.hasCommentBefore(end) {
+		for i := range starts {
+			if i > 0 {
+				p.buf.WriteString(", ")
+			}
+			item(i)
+		}
+		p.buf.WriteString(close)
+		return
+	}
+	p.buf.WriteString("\n")
+	p.indent++
+	first := true
+	for i, start := range starts {
+		pos := ast.Pos(start)
+		p.flushComments(pos, &first)
+		p.lineStart(pos.Line, &first)
+		item(i)
+		if i+1 < len(starts) {
+			p.buf.WriteString(",")
+		}
+		p.buf.WriteString("\n")
+	}
+	p.flushComments(end, &first)
+	p.indent--
+	p.buf.WriteString(strings.Repeat(INDENT, p.indent))
+	p.buf.WriteString(close)
+}
+
+func precedence(exp ast.Expression) int {
+	switch exp := exp.(type) {
+	case *ast.Infix:
+		return precedences[exp.Operator]
+	case *ast.PrefixExpression:
+		return PREFIX
+	case *ast.CallExpression:
+		return CALL
+	case *ast.MemberExpression:
+		return MEMBER
+	case *ast.IndexExpression:
+		return INDEX
+	default:
+		return PRIMARY
+	}
+}
@@ -0,0 +1,93 @@
+package printer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/shozawa/monkey/ast"
+	"github.com/shozawa/monkey/lexer"
+	"github.com/shozawa/monkey/parser"
+)
+
+func TestSprint(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"let x=1", "let x = 1;\n"},
+		{"1+2*3", "1 + 2 * 3;\n"},
+		{"(1+2)*3", "(1 + 2) * 3;\n"},
+		{"1-(2-3)", "1 - (2 - 3);\n"},
+		{"(1-2)-3", "1 - 2 - 3;\n"},
+		{"-(1+2)", "-(1 + 2);\n"},
+		{"!(a == b)", "!(a == b);\n"},
+		{"(a < b) == (c > d)", "a < b == c > d;\n"},
+		{`puts("a \"b\"\n")`, `puts("a \"b\"\n");` + "\n"},
+		{"m.f(1)(2)", "m.f(1)(2);\n"},
+		{"[1,2+3][0]", "[1, 2 + 3][0];\n"},
+		{"(-a)[0]", "(-a)[0];\n"},
+		{`let h={"a":1,b:fn(){}}`, `let h = {"a": 1, b: fn() {}};` + "\n"},
+		{"(-f)(1)", "(-f)(1);\n"},
+		{`split(/a\/b/,s)/2`, `split(/a\/b/, s) / 2;` + "\n"},
+		{"-9223372036854775808", "-9223372036854775808;\n"},
+		{"return", "return;\n"},
+		{"fn(){}", "fn() {};\n"},
+		{"let add=fn(a,b){return a+b}", "let add = fn(a, b) {\n    return a + b;\n};\n"},
+		{"if(a){1};-1;if(a){1};x", "if (a) {\n    1;\n};\n-1;\nif (a) {\n    1;\n}\nx;\n"},
+		{"if(x>1){x}else{if(y){1}}", "if (x > 1) {\n    x;\n} else {\n    if (y) {\n        1;\n    }\n}\n"},
+		{"map(xs, fn(x) { x * 2 })", "map(xs, fn(x) {\n    x * 2;\n});\n"},
+	}
+	for _, test := range tests {
+		program := parse(t, test.input)
+		if got := Sprint(&program); got != test.want {
+			t.Errorf("Sprint(%q) not %q. got=%q", test.input, test.want, got)
+		}
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	inputs := []string{
+		"let x = 1; let y = x * (2 + 3) % 4 / 5; y - -x;",
+		"!!true == !false; 1 != 2; a < b; (a > b) == false;",
+		`"quotes \" and \\ backslashes\n\ttabs";`,
+		"let f = fn(a, b) { let c = a + b; return c * (a - b); }; f(1, f(2, 3));",
+		"if (a) { 1 } else { if (b) { 2 } else { 3 } }; let v = if (x) { 1 } + 2;",
+		"fn(x) { fn(y) { x + y } }(1)(2); m.f(1).g.h(2); (a + b).c; -(a.b); (fn() { 1 })();",
+		`let h = {"a": [1], 2: {}}; h["a"][0]; {true: h}[true];`,
+		`let r = /^(\w+)\/(\d*)$/; replace_all(r, s, "$1") / 2; -/x/;`,
+		"let a = [1, [2, 3], []]; a[1][0]; m.xs[0]; f(1)[a[0]]; (-a)[0];",
+		"let fizzbuzz = fn(x) { if (x > 100) { return; } else { fizzbuzz(x + 1); } };",
+		"if (a) { 1 }; -1; if (a) { 1 } else { 2 }; [x]; if (a) { 1 }; (x);",
+		"if (a) { 1 }; (-x).y; if (a) { 1 }; [1][0] + 1; if (a) { 1 }; /x/; if (a) { 1 }; x; if (a) { 1 }",
+		"if (a) { 1 }; (fn() { 2 })(); if (b) { if (c) { 3 }; -4 }",
+	}
+	samples, _ := filepath.Glob(filepath.Join("..", "sample", "*.monkey"))
+	for _, path := range samples {
+		src, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		inputs = append(inputs, string(src))
+	}
+	for _, input := range inputs {
+		original := parse(t, input)
+		printed := Sprint(&original)
+		reparsed := parse(t, printed)
+		if got, want := reparsed.String(), original.String(); got != want {
+			t.Errorf("re-parsed tree differs.\ninput:    %q\nprinted:  %q\nwant:     %s\ngot:      %s", input, printed, want, got)
+		}
+		if again := Sprint(&reparsed); again != printed {
+			t.Errorf("printing is not idempotent.\nfirst:  %q\nsecond: %q", printed, again)
+		}
+	}
+}
+
+func parse(t *testing.T, input string) ast.Program {
+	p := parser.New(lexer.New(input))
+	program := p.Parse()
+	for _, msg := range p.Errors() {
+		t.Errorf("parser error for %q: %s", input, msg)
+	}
+	return program
+}
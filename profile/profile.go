@@ -0,0 +1,307 @@
+package profile
+
+import (
+	"compress/gzip"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shozawa/monkey/ast"
+	"github.com/shozawa/monkey/object"
+)
+
+// MAIN names the function standing for the top level of a program.
+const MAIN = "main"
+
+// Sample values recorded for each call stack, in pprof order.
+const (
+	VALUE_COUNT = iota // statements executed
+	VALUE_CALLS        // functions entered
+	VALUE_TIME         // nanoseconds spent
+	NUM_VALUES
+)
+
+var sampleTypes = [NUM_VALUES][2]string{
+	{"statements", "count"},
+	{"calls", "count"},
+	{"time", "nanoseconds"},
+}
+
+// function identifies a Monkey function by the let binding it was
+// created for and where its body starts.
+type function struct {
+	name string
+	file string
+	line int
+}
+
+type location struct {
+	fn   function
+	line int
+}
+
+type sample struct {
+	stack  []location // leaf first
+	values [NUM_VALUES]int64
+}
+
+// Profiler records how often each statement and function runs and how
+// long they take. Time is charged to the statement being executed when
+// it passes, so a line's time excludes the functions it calls.
+type Profiler struct {
+	now func() time.Time
+
+	mu      sync.Mutex
+	start   time.Time
+	samples map[string]*sample
+}
+
+func New() *Profiler {
+	return &Profiler{now: time.Now, samples: make(map[string]*sample)}
+}
+
+// Tracer returns the tracer to set on the runtime of the program being
+// profiled.
+func (p *Profiler) Tracer() object.Tracer {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.start.IsZero() {
+		p.start = p.now()
+	}
+	return &thread{profiler: p, last: p.now()}
+}
+
+// thread follows the call stack of one goroutine: the program's, or
+// one of the tasks it spawned.
+type thread struct {
+	profiler *Profiler
+	stack    []location
+	last     time.Time
+}
+
+// record adds values to the sample for the thread's current stack, and
+// charges it the time since the previous event.
+func (t *thread) record(value int) {
+	p := t.profiler
+	now := p.now()
+	elapsed := now.Sub(t.last)
+	t.last = now
+	if len(t.stack) == 0 {
+		return
+	}
+	keys := make([]string, len(t.stack))
+	for i, loc := range t.stack {
+		keys[i] = loc.fn.name + "@" + loc.fn.file + ":" + strconv.Itoa(loc.fn.line) + ":" + strconv.Itoa(loc.line)
+	}
+	key := strings.Join(keys, ";")
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	s, ok := p.samples[key]
+	if !ok {
+		s = &sample{stack: make([]location, len(t.stack))}
+		for i, loc := range t.stack {
+			s.stack[len(t.stack)-1-i] = loc
+		}
+		p.samples[key] = s
+	}
+	s.values[VALUE_TIME] += int64(elapsed)
+	if value >= 0 {
+		s.values[value]++
+	}
+}
+
+func (t *thread) Statement(stmt ast.Statement, env *object.Environment) {
+	if len(t.stack) == 0 {
+		t.stack = append(t.stack, location{fn: function{name: MAIN, file: fileName(env), line: 1}})
+	}
+	t.record(-1)
+	t.stack[len(t.stack)-1].line = ast.Pos(stmt).Line
+	t.record(VALUE_COUNT)
+}
+
+func (t *thread) Call(fn *object.Function, env *object.Environment) {
+	t.record(-1)
+	line := fn.Body.Token.Line
+	name := fn.Name
+	if name == "" {
+		// pprof drops names in angle brackets as C++ template
+		// arguments, so anonymous functions are named by their line.
+		name = "fn@" + strconv.Itoa(line)
+	}
+	t.stack = append(t.stack, location{fn: function{name: name, file: fileName(env), line: line}, line: line})
+	t.record(VALUE_CALLS)
+}
+
+func (t *thread) Return(fn *object.Function, result object.Object) {
+	t.record(-1)
+	if len(t.stack) > 0 {
+		t.stack = t.stack[:len(t.stack)-1]
+	}
+}
+
+func (t *thread) Branch(ie *ast.IfExpression, consequence bool, env *object.Environment) {}
+
+func (t *thread) Spawn() object.Tracer {
+	return &thread{profiler: t.profiler, stack: append([]location(nil), t.stack...), last: t.profiler.now()}
+}
+
+func fileName(env *object.Environment) string {
+	if f := env.File(); f != nil {
+		return f.Path
+	}
+	return ""
+}
+
+// Stats are the totals for one function or line. Time is the time spent
+// in the function's own statements, or on the line itself.
+type Stats struct {
+	Function string
+	File     string
+	Line     int
+	Count    int64
+	Time     time.Duration
+}
+
+// Functions returns the number of calls and the time of each function,
+// the most expensive first.
+func (p *Profiler) Functions() []Stats {
+	return p.totals(func(loc location) Stats {
+		return Stats{Function: loc.fn.name, File: loc.fn.file, Line: loc.fn.line}
+	}, VALUE_CALLS)
+}
+
+// Lines returns the number of executions and the time of each line,
+// the most expensive first.
+func (p *Profiler) Lines() []Stats {
+	return p.totals(func(loc location) Stats {
+		return Stats{Function: loc.fn.name, File: loc.fn.file, Line: loc.line}
+	}, VALUE_COUNT)
+}
+
+func (p *Profiler) totals(key func(location) Stats, count int) []Stats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	totals := make(map[Stats]*Stats)
+	for _, s := range p.samples {
+		k := key(s.stack[0])
+		total, ok := totals[k]
+		if !ok {
+			total = &Stats{Function: k.Function, File: k.File, Line: k.Line}
+			totals[k] = total
+		}
+		total.Count += s.values[count]
+		total.Time += time.Duration(s.values[VALUE_TIME])
+	}
+	stats := make([]Stats, 0, len(totals))
+	for _, total := range totals {
+		stats = append(stats, *total)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Time != stats[j].Time {
+			return stats[i].Time > stats[j].Time
+		}
+		if stats[i].File != stats[j].File {
+			return stats[i].File < stats[j].File
+		}
+		return stats[i].Line < stats[j].Line
+	})
+	return stats
+}
+
+// WritePprof writes the profile in the gzipped protocol buffer format
+// read by "go tool pprof".
+func (p *Profiler) WritePprof(w io.Writer) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	strs := []string{""}
+	strIndex := map[string]int64{"": 0}
+	str := func(s string) int64 {
+		if i, ok := strIndex[s]; ok {
+			return i
+		}
+		strIndex[s] = int64(len(strs))
+		strs = append(strs, s)
+		return strIndex[s]
+	}
+	functionIDs := make(map[function]uint64)
+	locationIDs := make(map[location]uint64)
+	var functions []function
+	var locations []location
+
+	keys := make([]string, 0, len(p.samples))
+	for key := range p.samples {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var b protoBuffer
+	for _, typ := range sampleTypes {
+		b.messageField(1, func(m *protoBuffer) {
+			m.int64Field(1, str(typ[0]))
+			m.int64Field(2, str(typ[1]))
+		})
+	}
+	for _, key := range keys {
+		s := p.samples[key]
+		ids := make([]uint64, len(s.stack))
+		for i, loc := range s.stack {
+			if _, ok := functionIDs[loc.fn]; !ok {
+				functions = append(functions, loc.fn)
+				functionIDs[loc.fn] = uint64(len(functions))
+			}
+			if _, ok := locationIDs[loc]; !ok {
+				locations = append(locations, loc)
+				locationIDs[loc] = uint64(len(locations))
+			}
+			ids[i] = locationIDs[loc]
+		}
+		b.messageField(2, func(m *protoBuffer) {
+			m.packedUint64s(1, ids)
+			m.packedInt64s(2, s.values[:])
+		})
+	}
+	for i, loc := range locations {
+		b.messageField(4, func(m *protoBuffer) {
+			m.uint64Field(1, uint64(i+1))
+			m.messageField(4, func(line *protoBuffer) {
+				line.uint64Field(1, functionIDs[loc.fn])
+				line.int64Field(2, int64(loc.line))
+			})
+		})
+	}
+	for i, fn := range functions {
+		b.messageField(5, func(m *protoBuffer) {
+			m.uint64Field(1, uint64(i+1))
+			m.int64Field(2, str(fn.name))
+			m.int64Field(3, str(fn.name))
+			m.int64Field(4, str(fn.file))
+			m.int64Field(5, int64(fn.line))
+		})
+	}
+	timeNanos := p.start.UnixNano()
+	duration := int64(p.now().Sub(p.start))
+	timeType := str(sampleTypes[VALUE_TIME][0])
+	nanoseconds := str(sampleTypes[VALUE_TIME][1])
+	// The string table must come after every str call above.
+	for _, s := range strs {
+		b.stringField(6, s)
+	}
+	b.int64Field(9, timeNanos)
+	b.int64Field(10, duration)
+	b.messageField(11, func(m *protoBuffer) {
+		m.int64Field(1, timeType)
+		m.int64Field(2, nanoseconds)
+	})
+	b.int64Field(12, 1)
+
+	zw := gzip.NewWriter(w)
+	if _, err := zw.Write(b.Bytes()); err != nil {
+		return err
+	}
+	return zw.Close()
+}
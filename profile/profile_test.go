@@ -0,0 +1,102 @@
+package profile
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shozawa/monkey/evaluator"
+	"github.com/shozawa/monkey/lexer"
+	"github.com/shozawa/monkey/object"
+	"github.com/shozawa/monkey/parser"
+)
+
+const testProgram = `let square = fn(x) {
+    x * x
+};
+let sum = fn(n) {
+    if (n == 0) {
+        return 0;
+    }
+    square(n) + sum(n - 1)
+};
+sum(3);
+`
+
+// profiled runs testProgram under a profiler whose clock advances one
+// millisecond each time it is read.
+func profiled(t *testing.T) *Profiler {
+	p := New()
+	clock := time.Unix(0, 0)
+	p.now = func() time.Time {
+		clock = clock.Add(time.Millisecond)
+		return clock
+	}
+	program := parser.New(lexer.New(testProgram)).Parse()
+	env := object.NewEnvWithRuntime(&object.Runtime{Tracer: p.Tracer()})
+	if result := evaluator.Eval(&program, env); result.Inspect() != "14" {
+		t.Fatalf("program result not 14. got=%s", result.Inspect())
+	}
+	return p
+}
+
+func TestFunctionsAndLines(t *testing.T) {
+	p := profiled(t)
+
+	calls := make(map[string]int64)
+	for _, stats := range p.Functions() {
+		calls[stats.Function] += stats.Count
+		if stats.Time <= 0 {
+			t.Errorf("function %s has no time", stats.Function)
+		}
+	}
+	for name, want := range map[string]int64{"sum": 4, "square": 3, MAIN: 0} {
+		if calls[name] != want {
+			t.Errorf("calls to %s not %d. got=%d", name, want, calls[name])
+		}
+	}
+
+	counts := make(map[int]int64)
+	for _, stats := range p.Lines() {
+		counts[stats.Line] += stats.Count
+	}
+	for line, want := range map[int]int64{1: 1, 2: 3, 4: 1, 5: 4, 6: 1, 8: 3, 10: 1} {
+		if counts[line] != want {
+			t.Errorf("executions of line %d not %d. got=%d", line, want, counts[line])
+		}
+	}
+}
+
+func TestAnonymousFunctions(t *testing.T) {
+	p := New()
+	program := parser.New(lexer.New("let xs = [1, 2];\nmap(xs, fn(x) { x * 2 });")).Parse()
+	env := object.NewEnvWithRuntime(&object.Runtime{Tracer: p.Tracer()})
+	evaluator.Eval(&program, env)
+	calls := make(map[string]int64)
+	for _, stats := range p.Functions() {
+		calls[stats.Function] += stats.Count
+	}
+	if calls["fn@2"] != 2 {
+		t.Errorf("calls to fn@2 not 2. got=%v", calls)
+	}
+}
+
+func TestWritePprof(t *testing.T) {
+	var buf bytes.Buffer
+	if err := profiled(t).WritePprof(&buf); err != nil {
+		t.Fatalf("WritePprof returned error: %s", err)
+	}
+	zr, err := gzip.NewReader(&buf)
+	if err != nil {
+		t.Fatalf("profile is not gzipped: %s", err)
+	}
+	raw, _ := io.ReadAll(zr)
+	for _, s := range []string{"statements", "calls", "nanoseconds", "square", "sum", MAIN} {
+		if !strings.Contains(string(raw), s) {
+			t.Errorf("profile has no string %q", s)
+		}
+	}
+}
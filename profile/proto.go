@@ -0,0 +1,72 @@
+package profile
+
+import "bytes"
+
+// protoBuffer encodes protocol buffer fields, enough of the wire format
+// to write profile.proto messages without depending on a protobuf
+// library.
+type protoBuffer struct {
+	bytes.Buffer
+}
+
+const (
+	WIRE_VARINT = 0
+	WIRE_BYTES  = 2
+)
+
+func (b *protoBuffer) varint(x uint64) {
+	for x >= 0x80 {
+		b.WriteByte(byte(x) | 0x80)
+		x >>= 7
+	}
+	b.WriteByte(byte(x))
+}
+
+func (b *protoBuffer) key(tag, wire int) {
+	b.varint(uint64(tag)<<3 | uint64(wire))
+}
+
+// uint64Field writes a scalar field, leaving out zero as proto3 does.
+func (b *protoBuffer) uint64Field(tag int, x uint64) {
+	if x == 0 {
+		return
+	}
+	b.key(tag, WIRE_VARINT)
+	b.varint(x)
+}
+
+func (b *protoBuffer) int64Field(tag int, x int64) {
+	b.uint64Field(tag, uint64(x))
+}
+
+func (b *protoBuffer) bytesField(tag int, p []byte) {
+	b.key(tag, WIRE_BYTES)
+	b.varint(uint64(len(p)))
+	b.Write(p)
+}
+
+func (b *protoBuffer) stringField(tag int, s string) {
+	b.bytesField(tag, []byte(s))
+}
+
+func (b *protoBuffer) messageField(tag int, encode func(m *protoBuffer)) {
+	var m protoBuffer
+	encode(&m)
+	b.bytesField(tag, m.Bytes())
+}
+
+func (b *protoBuffer) packedUint64s(tag int, xs []uint64) {
+	var m protoBuffer
+	for _, x := range xs {
+		m.varint(x)
+	}
+	b.bytesField(tag, m.Bytes())
+}
+
+func (b *protoBuffer) packedInt64s(tag int, xs []int64) {
+	var m protoBuffer
+	for _, x := range xs {
+		m.varint(uint64(x))
+	}
+	b.bytesField(tag, m.Bytes())
+}
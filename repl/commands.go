@@ -0,0 +1,184 @@
+package repl
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/shozawa/monkey/ast"
+	"github.com/shozawa/monkey/evaluator"
+	"github.com/shozawa/monkey/lexer"
+	"github.com/shozawa/monkey/object"
+	"github.com/shozawa/monkey/parser"
+	"github.com/shozawa/monkey/token"
+)
+
+const COMMAND_PREFIX = ":"
+
+type command struct {
+	usage string
+	help  string
+	run   func(s *session, arg string)
+}
+
+var commands map[string]command
+
+func init() {
+	commands = map[string]command{
+		"env":    {"", "list the bindings made in the session", (*session).cmdEnv},
+		"ast":    {"<expr>", "print the syntax tree of expr", (*session).cmdAST},
+		"tokens": {"<src>", "print the tokens of src", (*session).cmdTokens},
+		"load":   {"<file>", "evaluate file into the session", (*session).cmdLoad},
+		"reset":  {"", "clear the environment", (*session).cmdReset},
+		"time":   {"<expr>", "evaluate expr and report the time taken", (*session).cmdTime},
+		"help":   {"", "list the commands", (*session).cmdHelp},
+	}
+}
+
+// command runs a line like ":ast 1 + 2".
+func (s *session) command(line string) {
+	line = strings.TrimPrefix(line, COMMAND_PREFIX)
+	name, arg, _ := strings.Cut(strings.TrimSpace(line), " ")
+	cmd, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(s.errOut, "unknown command %s%s, try %shelp\n", COMMAND_PREFIX, name, COMMAND_PREFIX)
+		return
+	}
+	cmd.run(s, strings.TrimSpace(arg))
+}
+
+func (s *session) cmdEnv(arg string) {
+	for _, name := range s.env.LocalNames() {
+		value, _ := s.env.Get(name)
+		fmt.Fprintf(s.out, "%s: %s\n", name, value.Type())
+	}
+}
+
+func (s *session) cmdAST(arg string) {
+	program, ok := s.parse(arg)
+	if !ok {
+		return
+	}
+	for _, stmt := range program.Statements {
+		dumpNode(s, reflect.ValueOf(stmt), 0)
+	}
+}
+
+// dumpNode prints a node and its children, one per line, indented by
+// depth. It walks the node's fields so new node types need no changes.
+func dumpNode(s *session, v reflect.Value, depth int) {
+	indent := strings.Repeat("  ", depth)
+	if v.Kind() == reflect.Interface {
+		v = v.Elem()
+	}
+	if !v.IsValid() || v.Kind() == reflect.Ptr && v.IsNil() {
+		fmt.Fprintf(s.out, "%snil\n", indent)
+		return
+	}
+	elem := v.Elem()
+	fmt.Fprintf(s.out, "%s%s", indent, elem.Type().Name())
+	var children []reflect.Value
+	var labels []string
+	for i := 0; i < elem.NumField(); i++ {
+		field := elem.Type().Field(i)
+		value := elem.Field(i)
+		if field.Type == reflect.TypeOf(token.Token{}) {
+			continue
+		}
+		switch {
+		case field.Type.Implements(nodeType):
+			children = append(children, value)
+			labels = append(labels, field.Name)
+		case field.Type.Kind() == reflect.Slice && field.Type.Elem().Implements(nodeType):
+			for j := 0; j < value.Len(); j++ {
+				children = append(children, value.Index(j))
+				labels = append(labels, fmt.Sprintf("%s[%d]", field.Name, j))
+			}
+		default:
+			fmt.Fprintf(s.out, " %s=%q", field.Name, fmt.Sprint(value.Interface()))
+		}
+	}
+	fmt.Fprintln(s.out)
+	for i, child := range children {
+		fmt.Fprintf(s.out, "%s  %s:\n", indent, labels[i])
+		dumpNode(s, child, depth+2)
+	}
+}
+
+var nodeType = reflect.TypeOf((*ast.Node)(nil)).Elem()
+
+func (s *session) cmdTokens(arg string) {
+	l := lexer.New(arg)
+	for tok := l.NextToken(); ; tok = l.NextToken() {
+		fmt.Fprintf(s.out, "%-10s %q\n", tok.Type, tok.Literal)
+		if tok.Type == token.EOF {
+			return
+		}
+	}
+}
+
+func (s *session) cmdLoad(arg string) {
+	if arg == "" {
+		fmt.Fprintf(s.errOut, "usage: %sload <file>\n", COMMAND_PREFIX)
+		return
+	}
+	src, err := os.ReadFile(arg)
+	if err != nil {
+		fmt.Fprintln(s.errOut, err)
+		return
+	}
+	p := parser.New(lexer.New(string(src)))
+	program := p.Parse()
+	if errs := p.Errors(); len(errs) > 0 {
+		for _, msg := range errs {
+			fmt.Fprintf(s.errOut, "%s: %s\n", arg, msg)
+		}
+		return
+	}
+	// Imports in the file are resolved relative to it, as when running
+	// it.
+	file := s.env.File()
+	path, err := filepath.Abs(arg)
+	if err != nil {
+		fmt.Fprintln(s.errOut, err)
+		return
+	}
+	s.env.SetFile(&object.File{Path: path})
+	defer s.env.SetFile(file)
+	if result := evaluator.Eval(&program, s.env); result != nil && result.Type() == object.ERROR_OBJ {
+		s.print(result)
+	}
+}
+
+func (s *session) cmdReset(arg string) {
+	s.env = object.NewEnvWithRuntime(s.rt)
+}
+
+func (s *session) cmdTime(arg string) {
+	program, ok := s.parse(arg)
+	if !ok {
+		return
+	}
+	start := time.Now()
+	obj := evaluator.Eval(program, s.env)
+	elapsed := time.Since(start)
+	s.print(obj)
+	fmt.Fprintf(s.out, "elapsed: %s\n", elapsed)
+}
+
+func (s *session) cmdHelp(arg string) {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		cmd := commands[name]
+		usage := strings.TrimSpace(COMMAND_PREFIX + name + " " + cmd.usage)
+		fmt.Fprintf(s.out, "%-16s %s\n", usage, cmd.help)
+	}
+}
@@ -0,0 +1,355 @@
+package repl
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// ErrInterrupted is returned by ReadLine when the user presses Ctrl-C.
+var ErrInterrupted = errors.New("interrupted")
+
+const (
+	keyCtrlA     = 1
+	keyCtrlB     = 2
+	keyCtrlC     = 3
+	keyCtrlD     = 4
+	keyCtrlE     = 5
+	keyCtrlF     = 6
+	keyCtrlG     = 7
+	keyBackspace = 8
+	keyTab       = 9
+	keyCtrlK     = 11
+	keyCtrlL     = 12
+	keyEnter     = 13
+	keyCtrlN     = 14
+	keyCtrlP     = 16
+	keyCtrlR     = 18
+	keyCtrlU     = 21
+	keyEscape    = 27
+	keyDelete    = 127
+)
+
+// Escape sequences are decoded into runes from the private use area so
+// they can't be confused with typed characters.
+const (
+	keyUp rune = 0xE000 + iota
+	keyDown
+	keyRight
+	keyLeft
+	keyHome
+	keyEnd
+	keyDeleteForward
+	keyUnknown
+)
+
+// Completer returns the candidates for the identifier prefix being typed.
+type Completer func(prefix string) []string
+
+// LineEditor reads lines from a terminal in raw mode, supporting cursor
+// movement, history navigation, reverse search (Ctrl-R) and tab
+// completion. It only speaks VT100 escape sequences over the given
+// reader and writer, so tests can drive it with a fake terminal.
+type LineEditor struct {
+	in       *bufio.Reader
+	out      io.Writer
+	history  *History
+	complete Completer
+
+	prompt string
+	buf    []rune
+	pos    int
+}
+
+func NewLineEditor(in io.Reader, out io.Writer, history *History, complete Completer) *LineEditor {
+	if history == nil {
+		history = &History{}
+	}
+	return &LineEditor{
+		in:       bufio.NewReader(in),
+		out:      out,
+		history:  history,
+		complete: complete,
+	}
+}
+
+// ReadLine shows prompt and returns the line once Enter is pressed. It
+// returns io.EOF on Ctrl-D at an empty line and ErrInterrupted on Ctrl-C.
+func (e *LineEditor) ReadLine(prompt string) (string, error) {
+	e.prompt = prompt
+	e.buf = e.buf[:0]
+	e.pos = 0
+	histIdx := e.history.Len()
+	saved := ""
+	e.refresh()
+	for {
+		key, err := e.readKey()
+		if err != nil {
+			if err == io.EOF && len(e.buf) > 0 {
+				break
+			}
+			return "", err
+		}
+		switch key {
+		case keyEnter, '\n':
+			fmt.Fprint(e.out, "\r\n")
+			line := string(e.buf)
+			e.history.Add(line)
+			return line, nil
+		case keyCtrlC:
+			fmt.Fprint(e.out, "^C\r\n")
+			return "", ErrInterrupted
+		case keyCtrlD:
+			if len(e.buf) == 0 {
+				fmt.Fprint(e.out, "\r\n")
+				return "", io.EOF
+			}
+			e.deleteForward()
+		case keyBackspace, keyDelete:
+			if e.pos > 0 {
+				e.buf = append(e.buf[:e.pos-1], e.buf[e.pos:]...)
+				e.pos--
+			}
+		case keyDeleteForward:
+			e.deleteForward()
+		case keyLeft, keyCtrlB:
+			if e.pos > 0 {
+				e.pos--
+			}
+		case keyRight, keyCtrlF:
+			if e.pos < len(e.buf) {
+				e.pos++
+			}
+		case keyHome, keyCtrlA:
+			e.pos = 0
+		case keyEnd, keyCtrlE:
+			e.pos = len(e.buf)
+		case keyCtrlK:
+			e.buf = e.buf[:e.pos]
+		case keyCtrlU:
+			e.buf = append(e.buf[:0], e.buf[e.pos:]...)
+			e.pos = 0
+		case keyCtrlL:
+			fmt.Fprint(e.out, "\x1b[H\x1b[2J")
+		case keyUp, keyCtrlP:
+			if histIdx > 0 {
+				if histIdx == e.history.Len() {
+					saved = string(e.buf)
+				}
+				histIdx--
+				e.setLine(e.history.At(histIdx))
+			}
+		case keyDown, keyCtrlN:
+			if histIdx < e.history.Len() {
+				histIdx++
+				if histIdx == e.history.Len() {
+					e.setLine(saved)
+				} else {
+					e.setLine(e.history.At(histIdx))
+				}
+			}
+		case keyTab:
+			e.completeWord()
+		case keyCtrlR:
+			line, done, err := e.reverseSearch()
+			if err != nil {
+				return "", err
+			}
+			if done {
+				fmt.Fprint(e.out, "\r\n")
+				e.history.Add(line)
+				return line, nil
+			}
+		case keyCtrlG, keyEscape, keyUnknown:
+		default:
+			if unicode.IsPrint(key) {
+				e.insert(key)
+			}
+		}
+		e.refresh()
+	}
+	line := string(e.buf)
+	e.history.Add(line)
+	return line, nil
+}
+
+func (e *LineEditor) insert(r rune) {
+	e.buf = append(e.buf, 0)
+	copy(e.buf[e.pos+1:], e.buf[e.pos:])
+	e.buf[e.pos] = r
+	e.pos++
+}
+
+func (e *LineEditor) deleteForward() {
+	if e.pos < len(e.buf) {
+		e.buf = append(e.buf[:e.pos], e.buf[e.pos+1:]...)
+	}
+}
+
+func (e *LineEditor) setLine(line string) {
+	e.buf = []rune(line)
+	e.pos = len(e.buf)
+}
+
+func (e *LineEditor) refresh() {
+	fmt.Fprintf(e.out, "\r%s%s\x1b[K", e.prompt, string(e.buf))
+	if back := len(e.buf) - e.pos; back > 0 {
+		fmt.Fprintf(e.out, "\x1b[%dD", back)
+	}
+}
+
+// readKey reads one key press, decoding the escape sequences sent for
+// arrow, home, end and delete keys.
+func (e *LineEditor) readKey() (rune, error) {
+	r, _, err := e.in.ReadRune()
+	if err != nil || r != keyEscape {
+		return r, err
+	}
+	next, _, err := e.in.ReadRune()
+	if err != nil {
+		return keyEscape, nil
+	}
+	if next != '[' && next != 'O' {
+		return keyUnknown, nil
+	}
+	code, _, err := e.in.ReadRune()
+	if err != nil {
+		return keyUnknown, nil
+	}
+	switch code {
+	case 'A':
+		return keyUp, nil
+	case 'B':
+		return keyDown, nil
+	case 'C':
+		return keyRight, nil
+	case 'D':
+		return keyLeft, nil
+	case 'H':
+		return keyHome, nil
+	case 'F':
+		return keyEnd, nil
+	}
+	if code >= '0' && code <= '9' {
+		// ESC [ n ~
+		for {
+			r, _, err := e.in.ReadRune()
+			if err != nil || r == '~' {
+				break
+			}
+		}
+		switch code {
+		case '1', '7':
+			return keyHome, nil
+		case '4', '8':
+			return keyEnd, nil
+		case '3':
+			return keyDeleteForward, nil
+		}
+	}
+	return keyUnknown, nil
+}
+
+// reverseSearch runs an incremental search through the history. It
+// returns done when Enter accepted a match as the whole line; any other
+// key leaves the match in the buffer for further editing.
+func (e *LineEditor) reverseSearch() (line string, done bool, err error) {
+	original := string(e.buf)
+	query := ""
+	match := -1
+	from := e.history.Len() - 1
+	for {
+		found := ""
+		if match >= 0 {
+			found = e.history.At(match)
+		}
+		fmt.Fprintf(e.out, "\r(reverse-i-search)`%s': %s\x1b[K", query, found)
+
+		key, err := e.readKey()
+		if err != nil {
+			return "", false, err
+		}
+		switch key {
+		case keyEnter, '\n':
+			if match < 0 {
+				return original, true, nil
+			}
+			return found, true, nil
+		case keyCtrlR:
+			if match >= 0 {
+				from = match - 1
+			}
+		case keyCtrlG, keyCtrlC:
+			e.setLine(original)
+			return "", false, nil
+		case keyBackspace, keyDelete:
+			if query != "" {
+				query = string([]rune(query)[:len([]rune(query))-1])
+			}
+			from = e.history.Len() - 1
+		default:
+			if !unicode.IsPrint(key) {
+				if match >= 0 {
+					e.setLine(found)
+				}
+				return "", false, nil
+			}
+			query += string(key)
+			if match >= 0 {
+				from = match
+			}
+		}
+		if query == "" {
+			match = -1
+			continue
+		}
+		if m := e.history.Search(query, from); m >= 0 {
+			match = m
+		}
+	}
+}
+
+// completeWord completes the identifier before the cursor. A single
+// candidate is inserted in full; several are reduced to their common
+// prefix, and listed when that doesn't extend the word.
+func (e *LineEditor) completeWord() {
+	if e.complete == nil {
+		return
+	}
+	start := e.pos
+	for start > 0 && isIdentRune(e.buf[start-1]) {
+		start--
+	}
+	prefix := string(e.buf[start:e.pos])
+	if prefix == "" {
+		return
+	}
+	candidates := e.complete(prefix)
+	if len(candidates) == 0 {
+		return
+	}
+	common := candidates[0]
+	for _, c := range candidates[1:] {
+		for !strings.HasPrefix(c, common) {
+			common = common[:len(common)-1]
+		}
+	}
+	if len(common) > len(prefix) {
+		for _, r := range common[len(prefix):] {
+			e.insert(r)
+		}
+		return
+	}
+	if len(candidates) > 1 {
+		sort.Strings(candidates)
+		fmt.Fprintf(e.out, "\r\n%s\r\n", strings.Join(candidates, "  "))
+	}
+}
+
+func isIdentRune(r rune) bool {
+	return r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z'
+}
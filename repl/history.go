@@ -0,0 +1,136 @@
+package repl
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	HISTORY_FILE = ".monkey_history"
+	HISTORY_SIZE = 1000
+)
+
+// History keeps the last HISTORY_SIZE lines entered. When it has a
+// path, every added line is also appended to that file so it survives
+// restarts, and the file is rewritten with only the kept lines once it
+// holds twice as many.
+type History struct {
+	entries []string
+	path    string
+	// lines counts the lines in the file.
+	lines int
+}
+
+// DefaultHistoryPath returns the history dotfile in the user's home
+// directory, or "" when there is none.
+func DefaultHistoryPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, HISTORY_FILE)
+}
+
+// LoadHistory reads the history stored at path. A missing file is not
+// an error; it is created on the first Add.
+func LoadHistory(path string) (*History, error) {
+	h := &History{path: path}
+	if path == "" {
+		return h, nil
+	}
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return h, nil
+	}
+	if err != nil {
+		return h, err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		h.lines++
+		if line := scanner.Text(); line != "" {
+			h.entries = append(h.entries, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return h, err
+	}
+	if len(h.entries) > HISTORY_SIZE {
+		h.entries = h.entries[len(h.entries)-HISTORY_SIZE:]
+	}
+	if h.lines > HISTORY_SIZE {
+		return h, h.rewrite()
+	}
+	return h, nil
+}
+
+func (h *History) Add(line string) error {
+	line = strings.TrimRight(line, "\n")
+	if strings.TrimSpace(line) == "" {
+		return nil
+	}
+	if n := len(h.entries); n > 0 && h.entries[n-1] == line {
+		return nil
+	}
+	h.entries = append(h.entries, line)
+	if len(h.entries) > HISTORY_SIZE {
+		h.entries = h.entries[len(h.entries)-HISTORY_SIZE:]
+	}
+	if h.path == "" {
+		return nil
+	}
+	if h.lines >= 2*HISTORY_SIZE {
+		return h.rewrite()
+	}
+	f, err := os.OpenFile(h.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if _, err := f.WriteString(line + "\n"); err != nil {
+		return err
+	}
+	h.lines++
+	return nil
+}
+
+// rewrite replaces the file with the kept entries. It writes a
+// temporary file first so a failure leaves the old one intact.
+func (h *History) rewrite() error {
+	tmp := h.path + ".tmp"
+	content := strings.Join(h.entries, "\n") + "\n"
+	if err := os.WriteFile(tmp, []byte(content), 0600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, h.path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	h.lines = len(h.entries)
+	return nil
+}
+
+func (h *History) Len() int {
+	return len(h.entries)
+}
+
+func (h *History) At(i int) string {
+	return h.entries[i]
+}
+
+// Search looks backwards from index from for an entry containing query
+// and returns its index, or -1.
+func (h *History) Search(query string, from int) int {
+	if from >= len(h.entries) {
+		from = len(h.entries) - 1
+	}
+	for i := from; i >= 0; i-- {
+		if strings.Contains(h.entries[i], query) {
+			return i
+		}
+	}
+	return -1
+}
@@ -4,34 +4,213 @@ import (
 	"bufio"
 	"fmt"
 	"io"
+	"os"
+	"strings"
 
+	"github.com/shozawa/monkey/ast"
 	"github.com/shozawa/monkey/evaluator"
 	"github.com/shozawa/monkey/lexer"
 	"github.com/shozawa/monkey/object"
 	"github.com/shozawa/monkey/parser"
+	"github.com/shozawa/monkey/prelude"
+	"github.com/shozawa/monkey/token"
 )
 
-const PROMPT = ">> "
+const (
+	PROMPT          = ">> "
+	CONTINUE_PROMPT = ".. "
+)
+
+type lineReader interface {
+	ReadLine(prompt string) (string, error)
+}
+
+// scannerReader reads plain lines, for input that is not a terminal.
+type scannerReader struct {
+	scanner *bufio.Scanner
+	out     io.Writer
+}
+
+func (r *scannerReader) ReadLine(prompt string) (string, error) {
+	fmt.Fprint(r.out, prompt)
+	if !r.scanner.Scan() {
+		if err := r.scanner.Err(); err != nil {
+			return "", err
+		}
+		return "", io.EOF
+	}
+	return r.scanner.Text(), nil
+}
+
+// terminalReader runs a LineEditor with the terminal in raw mode while
+// a line is being edited.
+type terminalReader struct {
+	editor *LineEditor
+	fd     uintptr
+}
+
+func (r *terminalReader) ReadLine(prompt string) (string, error) {
+	restore, err := makeRaw(r.fd)
+	if err != nil {
+		return "", err
+	}
+	defer restore()
+	return r.editor.ReadLine(prompt)
+}
+
+func newLineReader(in io.Reader, out io.Writer, complete Completer) lineReader {
+	if f, ok := in.(*os.File); ok && isTerminal(f.Fd()) {
+		history, _ := LoadHistory(DefaultHistoryPath())
+		editor := NewLineEditor(in, out, history, complete)
+		return &terminalReader{editor: editor, fd: f.Fd()}
+	}
+	return &scannerReader{scanner: bufio.NewScanner(in), out: out}
+}
+
+// session is the state of one REPL run.
+type session struct {
+	rt     *object.Runtime
+	env    *object.Environment
+	out    io.Writer
+	errOut io.Writer
+	// status is the exit status the program asked for with exit.
+	status int
+}
+
+func newSession(out, errOut io.Writer, prelude *object.Environment) *session {
+	rt := &object.Runtime{
+		Capabilities: object.CAP_ALL,
+		Stdout:       out,
+		Stderr:       errOut,
+		Prelude:      prelude,
+	}
+	return &session{rt: rt, env: object.NewEnvWithRuntime(rt), out: out, errOut: errOut}
+}
+
+// complete offers the bindings visible in the session's environment and
+// the builtin function names.
+func (s *session) complete(prefix string) []string {
+	var candidates []string
+	seen := make(map[string]bool)
+	for _, names := range [][]string{s.env.Names(), evaluator.BuiltinNames()} {
+		for _, name := range names {
+			if strings.HasPrefix(name, prefix) && !seen[name] {
+				seen[name] = true
+				candidates = append(candidates, name)
+			}
+		}
+	}
+	return candidates
+}
 
-func Start(in io.Reader, out io.Writer) {
-	scanner := bufio.NewScanner(in)
-	env := object.NewEnv()
+// eval evaluates input and prints its value. It reports whether the
+// session goes on, which it does unless exit was called.
+func (s *session) eval(input string) bool {
+	program, ok := s.parse(input)
+	if !ok {
+		return true
+	}
+	obj := evaluator.Eval(program, s.env)
+	if errObj, ok := obj.(*object.Error); ok && errObj.Exit {
+		s.status = errObj.Code
+		return false
+	}
+	s.print(obj)
+	return true
+}
+
+// parse parses input, printing the syntax errors and reporting false
+// when there are any.
+func (s *session) parse(input string) (*ast.Program, bool) {
+	p := parser.New(lexer.New(input))
+	program := p.Parse()
+	if errs := p.Errors(); len(errs) > 0 {
+		for _, msg := range errs {
+			fmt.Fprintln(s.errOut, msg)
+		}
+		return nil, false
+	}
+	return &program, true
+}
+
+// print echoes the value of an evaluation. Statements such as let
+// produce no value and print nothing.
+func (s *session) print(obj object.Object) {
+	switch obj := obj.(type) {
+	case nil:
+	case *object.Error:
+		fmt.Fprintln(s.errOut, obj.Inspect())
+	default:
+		fmt.Fprintln(s.out, obj.Inspect())
+	}
+}
+
+// Config adjusts the REPL.
+type Config struct {
+	// NoPrelude is as in interpreter.Config.
+	NoPrelude bool
+}
+
+func Start(in io.Reader, out, errOut io.Writer) int {
+	return (&Config{}).Start(in, out, errOut)
+}
+
+// Start runs the REPL until its input ends or exit is called, and
+// returns the status given to exit, 0 otherwise.
+func (c *Config) Start(in io.Reader, out, errOut io.Writer) int {
+	var env *object.Environment
+	if !c.NoPrelude {
+		env = prelude.Env()
+	}
+	s := newSession(out, errOut, env)
+	lines := newLineReader(in, out, s.complete)
+	var input strings.Builder
 	for {
-		fmt.Printf(PROMPT)
-		scanned := scanner.Scan()
-		if !scanned {
-			return
-		}
-		line := scanner.Text()
-		l := lexer.New(line)
-		p := parser.New(l)
-		program := p.Parse()
-		obj := evaluator.Eval(&program, env)
-		if obj != nil {
-			fmt.Printf("%q\n", obj.Inspect())
-		} else {
-			// FIXME: print correct value
-			fmt.Print("nil\n")
+		prompt := PROMPT
+		if input.Len() > 0 {
+			prompt = CONTINUE_PROMPT
+		}
+		line, err := lines.ReadLine(prompt)
+		if err == ErrInterrupted {
+			input.Reset()
+			continue
+		}
+		if err != nil {
+			return 0
+		}
+		if input.Len() == 0 && strings.HasPrefix(line, COMMAND_PREFIX) {
+			s.command(line)
+			continue
+		}
+		input.WriteString(line)
+		input.WriteString("\n")
+		if incomplete(input.String()) {
+			continue
+		}
+		if !s.eval(input.String()) {
+			return s.status
+		}
+		input.Reset()
+	}
+}
+
+// incomplete reports whether input ends inside a string literal or has
+// braces, brackets or parentheses that are still open, so more lines are needed
+// before it can be parsed.
+func incomplete(input string) bool {
+	l := lexer.New(input)
+	depth := 0
+	for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+		switch tok.Type {
+		case token.LBRACE, token.LPAREN, token.LBRACKET:
+			depth++
+		case token.RBRACE, token.RPAREN, token.RBRACKET:
+			depth--
+		case token.ILLEGAL:
+			if strings.HasPrefix(tok.Literal, `"`) {
+				return true
+			}
 		}
 	}
+	return depth > 0
 }
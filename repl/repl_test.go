@@ -0,0 +1,355 @@
+package repl
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/shozawa/monkey/object"
+)
+
+func TestStartWritesToGivenWriters(t *testing.T) {
+	in := strings.NewReader("puts(\"hi\")\n1 + true\n")
+	var out, errOut bytes.Buffer
+	Start(in, &out, &errOut)
+
+	if got, want := out.String(), ">> hi\nnull\n>> >> "; got != want {
+		t.Errorf("out not %q. got=%q", want, got)
+	}
+	if got, want := errOut.String(), "ERROR: type mismatch: INTEGER + BOOLEAN\n"; got != want {
+		t.Errorf("errOut not %q. got=%q", want, got)
+	}
+}
+
+func TestStartWaitsForCompleteInput(t *testing.T) {
+	input := `let fizz = fn(x) {
+    if (x % 3 == 0) {
+        puts("FIZZ");
+    } else {
+        puts(x)
+    }
+}
+fizz(
+  9
+)
+puts("multi
+line")
+`
+	var out, errOut bytes.Buffer
+	Start(strings.NewReader(input), &out, &errOut)
+
+	want := ">> .. .. .. .. .. .. " +
+		">> .. .. FIZZ\nnull\n" +
+		">> .. multi\nline\nnull\n" +
+		">> "
+	if got := out.String(); got != want {
+		t.Errorf("out not %q. got=%q", want, got)
+	}
+	if got := errOut.String(); got != "" {
+		t.Errorf("errOut not empty. got=%q", got)
+	}
+}
+
+func TestIncomplete(t *testing.T) {
+	tests := []struct {
+		input string
+		want  bool
+	}{
+		{"1 + 2", false},
+		{"let f = fn(x) {", true},
+		{"let f = fn(x) { x }", false},
+		{"f(1,", true},
+		{"let xs = [1,", true},
+		{"xs[0]", false},
+		{`puts("abc`, true},
+		{`puts("abc")`, false},
+		{"}", false},
+	}
+	for _, test := range tests {
+		if got := incomplete(test.input); got != test.want {
+			t.Errorf("incomplete(%q) not %t. got=%t", test.input, test.want, got)
+		}
+	}
+}
+
+func TestPrintValues(t *testing.T) {
+	input := `let add = fn(x, y) { x + y };
+add
+add(1, 2)
+"say \"hi\"\n"
+len
+if (false) { 1 }
+let x = 5;
+`
+	var out, errOut bytes.Buffer
+	Start(strings.NewReader(input), &out, &errOut)
+	want := ">> " +
+		">> fn(x, y) { ... }\n" +
+		">> 3\n" +
+		">> \"say \\\"hi\\\"\\n\"\n" +
+		">> builtin function\n" +
+		">> null\n" +
+		">> >> "
+	if got := out.String(); got != want {
+		t.Errorf("out not %q. got=%q", want, got)
+	}
+}
+
+func TestPrelude(t *testing.T) {
+	tests := []struct {
+		config *Config
+		out    string
+		errOut string
+	}{
+		{&Config{}, ">> 3\n>> ", ""},
+		{&Config{NoPrelude: true}, ">> >> ", "ERROR: identifier not found: sum\n"},
+	}
+	for _, test := range tests {
+		var out, errOut bytes.Buffer
+		test.config.Start(strings.NewReader("sum([1, 2])\n"), &out, &errOut)
+		if got := out.String(); got != test.out {
+			t.Errorf("out not %q. got=%q", test.out, got)
+		}
+		if got := errOut.String(); got != test.errOut {
+			t.Errorf("errOut not %q. got=%q", test.errOut, got)
+		}
+	}
+}
+
+func TestExitEndsSession(t *testing.T) {
+	var out, errOut bytes.Buffer
+	if status := Start(strings.NewReader("1\nexit(3)\n2\n"), &out, &errOut); status != 3 {
+		t.Errorf("status not 3. got=%d", status)
+	}
+	if got, want := out.String(), ">> 1\n>> "; got != want {
+		t.Errorf("out not %q. got=%q", want, got)
+	}
+	if got := errOut.String(); got != "" {
+		t.Errorf("errOut not empty. got=%q", got)
+	}
+}
+
+func TestLineEditorEditing(t *testing.T) {
+	tests := []struct {
+		keys string
+		want string
+	}{
+		{"1 + 2\r", "1 + 2"},
+		{"12\x1b[D\x1b[D+\r", "+12"},
+		{"abc\x01x\x05y\r", "xabcy"},
+		{"abcd\x7f\x7f\r", "ab"},
+		{"abcd\x1b[D\x1b[D\x1b[3~\r", "abd"},
+		{"hello world\x01\x0b\r", ""},
+		{"hello world\x1b[D\x1b[D\x15\r", "ld"},
+		{"λx\x1b[D\x1b[Dy\r", "yλx"},
+	}
+	for _, test := range tests {
+		editor := NewLineEditor(strings.NewReader(test.keys), new(bytes.Buffer), nil, nil)
+		got, err := editor.ReadLine(PROMPT)
+		if err != nil {
+			t.Errorf("ReadLine(%q) returned error: %s", test.keys, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("ReadLine(%q) not %q. got=%q", test.keys, test.want, got)
+		}
+	}
+}
+
+func TestLineEditorControlKeys(t *testing.T) {
+	editor := NewLineEditor(strings.NewReader("abc\x03\x04"), new(bytes.Buffer), nil, nil)
+	if _, err := editor.ReadLine(PROMPT); err != ErrInterrupted {
+		t.Errorf("Ctrl-C did not interrupt. got=%v", err)
+	}
+	if _, err := editor.ReadLine(PROMPT); err != io.EOF {
+		t.Errorf("Ctrl-D on empty line did not return EOF. got=%v", err)
+	}
+}
+
+func TestLineEditorHistory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), HISTORY_FILE)
+	history, err := LoadHistory(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys := "let a = 1;\r" +
+		"let b = 2;\r" +
+		"puts(a)\r" +
+		"\x1b[A\x1b[A\r" + // up twice recalls "let b = 2;"
+		"\x1b[A\x1b[A\x1b[B\x7f\x7f3;\r" + // up, up, down, then edit
+		"x\x12let a\r" + // reverse search replaces the typed line
+		"\x12pu\x12\x1b[C!\r" // search, then move right to edit the match
+	editor := NewLineEditor(strings.NewReader(keys), new(bytes.Buffer), history, nil)
+	want := []string{
+		"let a = 1;",
+		"let b = 2;",
+		"puts(a)",
+		"let b = 2;",
+		"let b = 3;",
+		"let a = 1;",
+		"puts(a)!",
+	}
+	for i, w := range want {
+		got, err := editor.ReadLine(PROMPT)
+		if err != nil {
+			t.Fatalf("line %d: ReadLine returned error: %s", i, err)
+		}
+		if got != w {
+			t.Errorf("line %d not %q. got=%q", i, w, got)
+		}
+	}
+
+	reloaded, err := LoadHistory(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := reloaded.Len(); got != len(want) {
+		t.Fatalf("reloaded history has %d entries, want %d", got, len(want))
+	}
+	for i, w := range want {
+		if got := reloaded.At(i); got != w {
+			t.Errorf("history[%d] not %q. got=%q", i, w, got)
+		}
+	}
+}
+
+func TestHistoryFileIsTrimmed(t *testing.T) {
+	path := filepath.Join(t.TempDir(), HISTORY_FILE)
+	var lines []string
+	for i := 0; i < HISTORY_SIZE+5; i++ {
+		lines = append(lines, fmt.Sprintf("puts(%d)", i))
+	}
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	countLines := func() int {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return strings.Count(string(data), "\n")
+	}
+
+	history, err := LoadHistory(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := countLines(); got != HISTORY_SIZE {
+		t.Errorf("file not trimmed to %d lines on load. got=%d", HISTORY_SIZE, got)
+	}
+	if got := history.At(0); got != "puts(5)" {
+		t.Errorf("oldest entry not puts(5). got=%q", got)
+	}
+	for i := 0; i <= HISTORY_SIZE; i++ {
+		if err := history.Add(fmt.Sprintf("x + %d", i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := countLines(); got != HISTORY_SIZE {
+		t.Errorf("file not trimmed to %d lines after adding. got=%d", HISTORY_SIZE, got)
+	}
+	if got, want := history.At(history.Len()-1), fmt.Sprintf("x + %d", HISTORY_SIZE); got != want {
+		t.Errorf("newest entry not %q. got=%q", want, got)
+	}
+}
+
+func TestLineEditorCompletion(t *testing.T) {
+	s := newSession(new(bytes.Buffer), new(bytes.Buffer), nil)
+	s.env.Set("counter", &object.Integer{Value: 1})
+	s.env.Set("count_all", &object.Integer{Value: 2})
+	complete := s.complete
+
+	tests := []struct {
+		keys string
+		want string
+	}{
+		{"le\t(\"\")\r", "len(\"\")"},
+		{"pu\t\r", "puts"},
+		{"cou\t\r", "count"},
+		{"x + coun\t_\t\r", "x + count_all"},
+		{"zzz\t\r", "zzz"},
+	}
+	for _, test := range tests {
+		var out bytes.Buffer
+		editor := NewLineEditor(strings.NewReader(test.keys), &out, nil, complete)
+		got, err := editor.ReadLine(PROMPT)
+		if err != nil {
+			t.Errorf("ReadLine(%q) returned error: %s", test.keys, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("ReadLine(%q) not %q. got=%q", test.keys, test.want, got)
+		}
+	}
+
+	var out bytes.Buffer
+	editor := NewLineEditor(strings.NewReader("count\t\r"), &out, nil, complete)
+	editor.ReadLine(PROMPT)
+	if !strings.Contains(out.String(), "count_all  counter") {
+		t.Errorf("candidates not listed. got=%q", out.String())
+	}
+}
+
+func TestCommands(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "lib.monkey")
+	if err := os.WriteFile(file, []byte("let square = fn(x) { x * x };"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "main.monkey"), []byte(`let lib = import("lib.monkey");`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		input  string
+		out    string
+		errOut string
+	}{
+		{"let x = 1;\nlet f = fn(a) { a };\n:env\n", ">> >> >> f: FUNCTION\nx: INTEGER\n>> ", ""},
+		{":ast -1 + x\n", `>> ExpressionStatement
+  Expression:
+    Infix Operator="+"
+      Left:
+        PrefixExpression Operator="-"
+          Right:
+            IntegerLiteral Value="1"
+      Right:
+        Identifier Value="x"
+>> `, ""},
+		{":tokens let a = \"s\";\n", `>> LET        "let"
+IDENT      "a"
+ASSIGN     "="
+STRING     "s"
+SEMICOLON  ";"
+EOF        ""
+>> `, ""},
+		{":load " + file + "\nsquare(4)\n", ">> >> 16\n>> ", ""},
+		{":load " + filepath.Join(dir, "main.monkey") + "\nlib.square(5)\n", ">> >> 25\n>> ", ""},
+		{":load missing.monkey\n", ">> >> ", "open missing.monkey: no such file or directory\n"},
+		{"let x = 1;\n:reset\nx\n", ">> >> >> >> ", "ERROR: identifier not found: x\n"},
+		{":nope\n", ">> >> ", "unknown command :nope, try :help\n"},
+		{"let x = 1 +;\nx\n", ">> >> >> ", "1:12: no prefix parse function for SEMICOLON \";\" found\nERROR: identifier not found: x\n"},
+		{":time 1 +\n", ">> >> ", "1:4: no prefix parse function for EOF \"\" found\n"},
+	}
+	for _, test := range tests {
+		var out, errOut bytes.Buffer
+		Start(strings.NewReader(test.input), &out, &errOut)
+		if got := out.String(); got != test.out {
+			t.Errorf("input %q: out not %q. got=%q", test.input, test.out, got)
+		}
+		if got := errOut.String(); got != test.errOut {
+			t.Errorf("input %q: errOut not %q. got=%q", test.input, test.errOut, got)
+		}
+	}
+}
+
+func TestTimeCommand(t *testing.T) {
+	var out, errOut bytes.Buffer
+	Start(strings.NewReader(":time 6 * 7\n"), &out, &errOut)
+	if got := out.String(); !strings.HasPrefix(got, ">> 42\nelapsed: ") {
+		t.Errorf("unexpected :time output. got=%q", got)
+	}
+}
@@ -0,0 +1,51 @@
+//go:build linux
+
+package repl
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+func getTermios(fd uintptr) (*syscall.Termios, error) {
+	var t syscall.Termios
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, syscall.TCGETS, uintptr(unsafe.Pointer(&t)))
+	if errno != 0 {
+		return nil, errno
+	}
+	return &t, nil
+}
+
+func setTermios(fd uintptr, t *syscall.Termios) error {
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, syscall.TCSETS, uintptr(unsafe.Pointer(t)))
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+func isTerminal(fd uintptr) bool {
+	_, err := getTermios(fd)
+	return err == nil
+}
+
+// makeRaw switches the terminal to raw input mode and returns a function
+// restoring the previous state. Output processing is left on so program
+// output keeps its newline translation.
+func makeRaw(fd uintptr) (func(), error) {
+	old, err := getTermios(fd)
+	if err != nil {
+		return nil, err
+	}
+	raw := *old
+	raw.Iflag &^= syscall.IGNBRK | syscall.BRKINT | syscall.PARMRK | syscall.ISTRIP | syscall.INLCR | syscall.IGNCR | syscall.ICRNL | syscall.IXON
+	raw.Lflag &^= syscall.ECHO | syscall.ECHONL | syscall.ICANON | syscall.ISIG | syscall.IEXTEN
+	raw.Cflag &^= syscall.CSIZE | syscall.PARENB
+	raw.Cflag |= syscall.CS8
+	raw.Cc[syscall.VMIN] = 1
+	raw.Cc[syscall.VTIME] = 0
+	if err := setTermios(fd, &raw); err != nil {
+		return nil, err
+	}
+	return func() { setTermios(fd, old) }, nil
+}
@@ -0,0 +1,13 @@
+//go:build !linux
+
+package repl
+
+import "errors"
+
+func isTerminal(fd uintptr) bool {
+	return false
+}
+
+func makeRaw(fd uintptr) (func(), error) {
+	return nil, errors.New("raw terminal mode not supported")
+}
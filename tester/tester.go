@@ -0,0 +1,231 @@
+package tester
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/shozawa/monkey/ast"
+	"github.com/shozawa/monkey/evaluator"
+	"github.com/shozawa/monkey/lexer"
+	"github.com/shozawa/monkey/object"
+	"github.com/shozawa/monkey/parser"
+	"github.com/shozawa/monkey/prelude"
+	"github.com/shozawa/monkey/token"
+)
+
+// TEST_SUFFIX ends the names of the files holding tests, and TEST_PREFIX
+// the names of the test functions in them.
+const (
+	TEST_SUFFIX = "_test.monkey"
+	TEST_PREFIX = "test_"
+)
+
+// Runner runs test files, reporting each to Out.
+type Runner struct {
+	Out io.Writer
+	// Verbose reports passing tests too.
+	Verbose bool
+	// Tracer, when set, observes the tests, as coverage tools do.
+	Tracer object.Tracer
+	// NoPrelude is as in interpreter.Config.
+	NoPrelude bool
+}
+
+// test is a top-level function of a test file to run.
+type test struct {
+	name string
+	pos  token.Position
+}
+
+// RunFile runs the tests in the file at path and reports whether they
+// all passed. Each top-level function named test_... is a test, run
+// with no arguments in a fresh environment where the rest of the file
+// has been evaluated. It passes unless it returns an error, as failed
+// assertions do. A file without test functions passes when it runs
+// without error.
+func (r *Runner) RunFile(path string) bool {
+	code, err := os.ReadFile(path)
+	if err != nil {
+		r.fail(path, err.Error())
+		return false
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		r.fail(path, err.Error())
+		return false
+	}
+	p := parser.New(lexer.New(string(code)))
+	program := p.Parse()
+	if errs := p.ErrorList(); len(errs) > 0 {
+		var msgs []string
+		for _, e := range errs {
+			msgs = append(msgs, path+":"+e.String())
+		}
+		r.fail(path, strings.Join(msgs, "\n"))
+		return false
+	}
+
+	var tests []test
+	for _, stmt := range program.Statements {
+		let, ok := stmt.(*ast.LetStatement)
+		if !ok || !strings.HasPrefix(let.Name.Value, TEST_PREFIX) {
+			continue
+		}
+		if _, ok := let.Value.(*ast.FunctionLiteral); ok {
+			tests = append(tests, test{name: let.Name.Value, pos: ast.Pos(let)})
+		}
+	}
+	if len(tests) == 0 {
+		_, errMsg := r.run(&program, abs, "")
+		if errMsg != "" {
+			r.fail(path, errMsg)
+			return false
+		}
+		fmt.Fprintf(r.Out, "ok   %s [no tests]\n", path)
+		return true
+	}
+
+	failed := 0
+	for _, t := range tests {
+		passed, errMsg := r.run(&program, abs, t.name)
+		if !passed {
+			failed++
+			fmt.Fprintf(r.Out, "--- FAIL: %s (%s:%s)\n", t.name, path, t.pos)
+			fmt.Fprintf(r.Out, "    %s\n", indent(errMsg))
+		} else if r.Verbose {
+			fmt.Fprintf(r.Out, "--- PASS: %s (%s:%s)\n", t.name, path, t.pos)
+		}
+	}
+	if failed > 0 {
+		fmt.Fprintf(r.Out, "FAIL %s (%d of %d tests failed)\n", path, failed, len(tests))
+		return false
+	}
+	fmt.Fprintf(r.Out, "ok   %s (%d tests)\n", path, len(tests))
+	return true
+}
+
+// run evaluates program in a fresh environment, then calls the test
+// function called name unless name is empty. On failure it returns the
+// error with the position of the statement that raised it.
+func (r *Runner) run(program *ast.Program, path, name string) (bool, string) {
+	where := &position{tracer: r.Tracer}
+	rt := &object.Runtime{
+		Capabilities: object.CAP_ALL,
+		Stdout:       r.Out,
+		Stderr:       r.Out,
+		Tracer:       where,
+	}
+	if !r.NoPrelude {
+		rt.Prelude = prelude.Env()
+	}
+	env := object.NewEnvWithRuntime(rt)
+	env.SetFile(&object.File{Path: path})
+	result := evaluator.Eval(program, env)
+	if name != "" && !isError(result) {
+		fn, _ := env.Get(name)
+		result = evaluator.Apply(fn, nil, env)
+	}
+	if errObj, ok := result.(*object.Error); ok {
+		return false, where.String() + ": " + errObj.Message
+	}
+	return true, ""
+}
+
+func isError(obj object.Object) bool {
+	_, ok := obj.(*object.Error)
+	return ok
+}
+
+func (r *Runner) fail(path, msg string) {
+	fmt.Fprintf(r.Out, "FAIL %s\n    %s\n", path, indent(msg))
+}
+
+func indent(msg string) string {
+	return strings.ReplaceAll(msg, "\n", "\n    ")
+}
+
+type location struct {
+	file string
+	pos  token.Position
+}
+
+// position is a tracer keeping track of where an error comes from
+// before passing each event on to tracer: the statement being run, or
+// the one in a called function that returned the error.
+type position struct {
+	tracer  object.Tracer
+	current location
+	callers []location
+	err     *location
+}
+
+func (p *position) String() string {
+	loc := p.current
+	if p.err != nil {
+		loc = *p.err
+	}
+	return fmt.Sprintf("%s:%s", loc.file, loc.pos)
+}
+
+func (p *position) Statement(stmt ast.Statement, env *object.Environment) {
+	p.current = location{pos: ast.Pos(stmt)}
+	if f := env.File(); f != nil {
+		p.current.file = relative(f.Path)
+	}
+	p.err = nil
+	if p.tracer != nil {
+		p.tracer.Statement(stmt, env)
+	}
+}
+
+func (p *position) Call(fn *object.Function, env *object.Environment) {
+	p.callers = append(p.callers, p.current)
+	if p.tracer != nil {
+		p.tracer.Call(fn, env)
+	}
+}
+
+func (p *position) Return(fn *object.Function, result object.Object) {
+	if isError(result) && p.err == nil {
+		loc := p.current
+		p.err = &loc
+	}
+	if n := len(p.callers); n > 0 {
+		p.current = p.callers[n-1]
+		p.callers = p.callers[:n-1]
+	}
+	if p.tracer != nil {
+		p.tracer.Return(fn, result)
+	}
+}
+
+func (p *position) Branch(ie *ast.IfExpression, consequence bool, env *object.Environment) {
+	if p.tracer != nil {
+		p.tracer.Branch(ie, consequence, env)
+	}
+}
+
+// Spawn hands tasks the underlying tracer: an error in a task surfaces
+// where it is awaited.
+func (p *position) Spawn() object.Tracer {
+	if p.tracer != nil {
+		return p.tracer.Spawn()
+	}
+	return nil
+}
+
+// relative shortens path to be relative to the working directory when
+// it is inside it.
+func relative(path string) string {
+	wd, err := os.Getwd()
+	if err != nil {
+		return path
+	}
+	if rel, err := filepath.Rel(wd, path); err == nil && !strings.HasPrefix(rel, "..") {
+		return rel
+	}
+	return path
+}
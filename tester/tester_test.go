@@ -0,0 +1,82 @@
+package tester
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRunFile(t *testing.T) {
+	tests := []struct {
+		name   string
+		code   string
+		passed bool
+		want   string
+	}{
+		{"pass", `let double = fn(x) { x * 2 };
+let test_double = fn() {
+    assert_eq(double(2), 4);
+};
+let test_zero = fn() { assert_eq(double(0), 0) };
+`, true, "ok   %s (2 tests)\n"},
+		{"fail", `let count = 0;
+let test_fresh = fn() {
+    assert_eq(count, 0);
+};
+let test_fails = fn() {
+    let x = 1;
+    assert(x > 1, "x too small");
+};
+let test_error = fn() { 1 + true };
+let helper = fn() { assert(false) };
+let test_helper = fn() {
+    helper();
+};
+`, false, `--- FAIL: test_fails (%[1]s:5:1)
+    %[1]s:7:5: assertion failed: x too small
+--- FAIL: test_error (%[1]s:9:1)
+    %[1]s:9:25: type mismatch: INTEGER + BOOLEAN
+--- FAIL: test_helper (%[1]s:11:1)
+    %[1]s:10:21: assertion failed
+FAIL %[1]s (3 of 4 tests failed)
+`},
+		{"diff", `let test_eq = fn() {
+    assert_eq(2, 3);
+};
+`, false, `--- FAIL: test_eq (%[1]s:1:1)
+    %[1]s:2:5: assert_eq failed:
+    --- want
+    +++ got
+    @@ -1,1 +1,1 @@
+    -3
+    +2
+FAIL %[1]s (1 of 1 tests failed)
+`},
+		{"no tests", "let x = 1;\n", true, "ok   %s [no tests]\n"},
+		{"top level error", "let x = y;\nlet test_x = fn() { x };\n", false, `--- FAIL: test_x (%[1]s:2:1)
+    %[1]s:1:1: identifier not found: y
+FAIL %[1]s (1 of 1 tests failed)
+`},
+		{"syntax error", "let = 1;\n", false, `FAIL %[1]s
+    %[1]s:1:5: expected next token to be IDENT, got ASSIGN instead
+    %[1]s:1:5: no prefix parse function for ASSIGN "=" found
+`},
+	}
+	for _, test := range tests {
+		path := filepath.Join(t.TempDir(), "x_test.monkey")
+		if err := os.WriteFile(path, []byte(test.code), 0644); err != nil {
+			t.Fatal(err)
+		}
+		var out bytes.Buffer
+		r := &Runner{Out: &out}
+		if passed := r.RunFile(path); passed != test.passed {
+			t.Errorf("%s: passed not %t. got=%t", test.name, test.passed, passed)
+		}
+		want := fmt.Sprintf(test.want, path)
+		if out.String() != want {
+			t.Errorf("%s: output not\n%s\ngot=\n%s", test.name, want, out.String())
+		}
+	}
+}
@@ -1,10 +1,33 @@
 package token
 
+import "fmt"
+
 type TokenType string
 
 type Token struct {
 	Type    TokenType
 	Literal string
+	Line    int
+	Column  int
+}
+
+func (t Token) Position() Position {
+	return Position{Line: t.Line, Column: t.Column}
+}
+
+// Position is a 1-based line and column in the source.
+type Position struct {
+	Line   int
+	Column int
+}
+
+func (p Position) String() string {
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
+}
+
+// Before reports whether p comes before q in the source.
+func (p Position) Before(q Position) bool {
+	return p.Line < q.Line || p.Line == q.Line && p.Column < q.Column
 }
 
 var keywords = map[string]TokenType{
@@ -21,7 +44,9 @@ const (
 	ILLEGAL = "ILLEGAL"
 	EOF     = "EOF"
 
-	STRING = "STRING"
+	STRING  = "STRING"
+	REGEX   = "REGEX"
+	COMMENT = "COMMENT"
 
 	IDENT  = "IDENT"
 	INT    = "INT"
@@ -36,12 +61,17 @@ const (
 
 	COMMA     = "COMMA"
 	SEMICOLON = "SEMICOLON"
+	COLON     = "COLON"
+	DOT       = "DOT"
 
 	LPAREN = "("
 	RPAREN = ")"
 	LBRACE = "{"
 	RBRACE = "}"
 
+	LBRACKET = "["
+	RBRACKET = "]"
+
 	FUNCTION = "FUNCTION"
 	LET      = "LET"
 	IF       = "IF"